@@ -0,0 +1,208 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	_ "github.com/bootdotdev/learn-pub-sub-starter/internal/gamelogic"
+	"github.com/bootdotdev/learn-pub-sub-starter/internal/routing"
+	"github.com/bootdotdev/learn-pub-sub-starter/internal/schema"
+
+	"gopkg.in/yaml.v3"
+)
+
+const amqpBindingVersion = "0.2.0"
+
+func main() {
+	output := flag.String("o", "", "write the document to this file instead of stdout")
+	format := flag.String("format", "yaml", "output format: yaml or json")
+	flag.Parse()
+
+	doc, err := buildDocument(routing.Routes())
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	var data []byte
+	switch *format {
+	case "yaml":
+		var buf bytes.Buffer
+		enc := yaml.NewEncoder(&buf)
+		enc.SetIndent(2)
+		err = enc.Encode(doc)
+		data = buf.Bytes()
+	case "json":
+		data, err = json.MarshalIndent(doc, "", "  ")
+		data = append(data, '\n')
+	default:
+		log.Fatalf("unknown format: %s", *format)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if *output == "" {
+		os.Stdout.Write(data)
+		return
+	}
+	err = os.WriteFile(*output, data, 0644)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("Wrote AsyncAPI document for %d routes to %s\n", len(routing.Routes()), *output)
+}
+
+func buildDocument(routes []routing.Route) (map[string]any, error) {
+	channels := map[string]any{}
+	messages := map[string]any{}
+	schemas := map[string]any{}
+
+	for _, r := range routes {
+		payload, ok := schema.Lookup(r.Payload)
+		if !ok {
+			return nil, fmt.Errorf("route %s uses unregistered payload %s", r.Name, r.Payload)
+		}
+		schemas[r.Payload] = schema.JSONSchema(payload)
+		messages[r.Payload] = map[string]any{
+			"name":        r.Payload,
+			"contentType": r.ContentType,
+			"headers":     headersSchema(payload),
+			"payload":     map[string]any{"$ref": "#/components/schemas/" + r.Payload},
+		}
+
+		queue := map[string]any{
+			"name":       r.Queue,
+			"bindingKey": r.BindingKey,
+			"consumer":   r.Subscriber,
+			"durable":    r.Durable,
+			"exclusive":  !r.Durable,
+			"autoDelete": !r.Durable,
+		}
+		consumer := fmt.Sprintf("the %s from queue %s bound with %s", r.Subscriber, r.Queue, r.BindingKey)
+
+		// Several consumers can bind their own queues to the same key.
+		if existing, ok := channels[r.Key]; ok {
+			channel := existing.(map[string]any)
+			channel["x-queues"] = append(channel["x-queues"].([]map[string]any), queue)
+			sub := channel["subscribe"].(map[string]any)
+			sub["summary"] = strings.TrimSuffix(sub["summary"].(string), ".") + "; by " + consumer + "."
+			bindings := sub["bindings"].(map[string]any)["amqp"].(map[string]any)
+			bindings["cc"] = appendUnique(bindings["cc"].([]string), r.BindingKey)
+			continue
+		}
+
+		ref := map[string]any{"$ref": "#/components/messages/" + r.Payload}
+		channel := map[string]any{
+			"description": r.Description,
+			"bindings": map[string]any{
+				"amqp": map[string]any{
+					"is": "routingKey",
+					"exchange": map[string]any{
+						"name":       r.Exchange,
+						"type":       r.ExchangeType,
+						"durable":    true,
+						"autoDelete": false,
+						"vhost":      "/",
+					},
+					"bindingVersion": amqpBindingVersion,
+				},
+			},
+			"publish": map[string]any{
+				"operationId": "publish_" + r.Name,
+				"summary":     fmt.Sprintf("Published by the %s.", r.Publisher),
+				"message":     ref,
+			},
+			"subscribe": map[string]any{
+				"operationId": "consume_" + r.Name,
+				"summary":     "Consumed by " + consumer + ".",
+				"message":     ref,
+				"bindings": map[string]any{
+					"amqp": map[string]any{
+						"ack":            true,
+						"cc":             []string{r.BindingKey},
+						"bindingVersion": amqpBindingVersion,
+					},
+				},
+			},
+			"x-queues": []map[string]any{queue},
+		}
+		if r.Reply != "" {
+			reply, ok := schema.Lookup(r.Reply)
+			if !ok {
+				return nil, fmt.Errorf("route %s uses unregistered reply %s", r.Name, r.Reply)
+			}
+			schemas[r.Reply] = schema.JSONSchema(reply)
+			messages[r.Reply] = map[string]any{
+				"name":        r.Reply,
+				"contentType": r.ContentType,
+				"headers":     headersSchema(reply),
+				"payload":     map[string]any{"$ref": "#/components/schemas/" + r.Reply},
+			}
+			channel["x-reply"] = map[string]any{
+				"address": "amq.rabbitmq.reply-to",
+				"message": map[string]any{"$ref": "#/components/messages/" + r.Reply},
+			}
+		}
+		if params := channelParameters(r.Key); len(params) > 0 {
+			channel["parameters"] = params
+		}
+		channels[r.Key] = channel
+	}
+
+	return map[string]any{
+		"asyncapi": "2.6.0",
+		"info": map[string]any{
+			"title":       "Peril",
+			"version":     "1.0.0",
+			"description": "Messaging contract between the Peril server and clients.",
+		},
+		"defaultContentType": routing.ContentTypeJSON,
+		"servers": map[string]any{
+			"local": map[string]any{
+				"url":      "localhost:5672",
+				"protocol": "amqp",
+			},
+		},
+		"channels": channels,
+		"components": map[string]any{
+			"messages": messages,
+			"schemas":  schemas,
+		},
+	}, nil
+}
+
+func headersSchema(v schema.Versioned) map[string]any {
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			schema.HeaderName:    map[string]any{"type": "string", "const": v.SchemaName()},
+			schema.HeaderVersion: map[string]any{"type": "integer", "const": v.SchemaVersion()},
+		},
+	}
+}
+
+func channelParameters(key string) map[string]any {
+	params := map[string]any{}
+	for _, word := range strings.Split(key, ".") {
+		if strings.HasPrefix(word, "{") && strings.HasSuffix(word, "}") {
+			params[strings.Trim(word, "{}")] = map[string]any{
+				"schema": map[string]any{"type": "string"},
+			}
+		}
+	}
+	return params
+}
+
+func appendUnique(list []string, val string) []string {
+	for _, v := range list {
+		if v == val {
+			return list
+		}
+	}
+	return append(list, val)
+}
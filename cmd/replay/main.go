@@ -0,0 +1,102 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/bootdotdev/learn-pub-sub-starter/internal/gamelogic"
+)
+
+func main() {
+	scenarioPath := flag.String("scenario", "", "scenario the match was played in, if not the built-in classic map")
+	speed := flag.Duration("speed", 0, "play a step every interval, such as 2s, instead of waiting for enter")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: replay [flags] matches/<game>-<time>.jsonl\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	entries, err := gamelogic.ReadMatch(flag.Arg(0))
+	if err != nil {
+		log.Fatal(err)
+	}
+	sc := gamelogic.DefaultScenario()
+	if *scenarioPath != "" {
+		sc, err = gamelogic.LoadScenario(*scenarioPath)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+	for _, e := range entries {
+		if e.Kind == gamelogic.MatchStarted && e.ScenarioHash != sc.Hash() {
+			log.Fatalf("the match was played in a different scenario (%.12s) than %s (%s)", e.ScenarioHash, sc.Name, sc.ShortHash())
+		}
+	}
+	steps := gamelogic.MatchSteps(entries)
+	if len(steps) == 0 {
+		log.Fatal("the match log is empty")
+	}
+
+	if *speed > 0 {
+		for i := range steps {
+			show(sc, steps, i)
+			time.Sleep(*speed)
+		}
+		return
+	}
+
+	fmt.Println("Press enter for the next step, or type back, a step number or quit.")
+	step := 0
+	for {
+		show(sc, steps, step)
+		input := gamelogic.GetInput()
+		if input == nil {
+			return
+		}
+		switch {
+		case len(input) == 0 || input[0] == "next":
+			if step == len(steps)-1 {
+				fmt.Println("That was the last step.")
+				continue
+			}
+			step++
+		case input[0] == "back":
+			if step > 0 {
+				step--
+			}
+		case input[0] == "quit":
+			return
+		default:
+			n, err := strconv.Atoi(input[0])
+			if err != nil || n < 1 || n > len(steps) {
+				fmt.Printf("Please enter a step between 1 and %d\n", len(steps))
+				continue
+			}
+			step = n - 1
+		}
+	}
+}
+
+// show draws the board as it was after the given step, and what happened
+// during it.
+func show(sc *gamelogic.Scenario, steps [][]gamelogic.MatchEntry, step int) {
+	board := gamelogic.NewBoard()
+	for _, entries := range steps[:step+1] {
+		for _, e := range entries {
+			board.Apply(e)
+		}
+	}
+	fmt.Printf("\n=== Step %d of %d ===\n", step+1, len(steps))
+	for _, e := range steps[step] {
+		fmt.Printf("%s %s\n", e.At.Local().Format(time.TimeOnly), e.Message)
+	}
+	fmt.Println()
+	board.Render(os.Stdout, sc)
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	_ "github.com/bootdotdev/learn-pub-sub-starter/internal/gamelogic"
+	_ "github.com/bootdotdev/learn-pub-sub-starter/internal/routing"
+	"github.com/bootdotdev/learn-pub-sub-starter/internal/schema"
+)
+
+func main() {
+	lockPath := flag.String("lock", "schema.lock.json", "path to the lock file describing deployed message schemas")
+	update := flag.Bool("update", false, "rewrite the lock file from the current message structs")
+	flag.Parse()
+
+	msgs := schema.Messages()
+	if *update {
+		err := schema.WriteLock(*lockPath, schema.NewLock(msgs))
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("Wrote %d message schemas to %s\n", len(msgs), *lockPath)
+		return
+	}
+
+	lock, err := schema.ReadLock(*lockPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+	problems := schema.Check(lock, msgs)
+	if len(problems) > 0 {
+		fmt.Println("Incompatible message schema changes:")
+		for _, p := range problems {
+			fmt.Printf("* %s\n", p)
+		}
+		os.Exit(1)
+	}
+	fmt.Printf("All %d message schemas are compatible with %s\n", len(msgs), *lockPath)
+}
@@ -13,11 +13,18 @@ import (
 	"github.com/stretchr/testify/require"
 )
 
-func spawnProcess(t *testing.T, name string, args ...string) (io.ReadWriteCloser, *exec.Cmd) {
+// terminal reads a process's output through a single buffered reader, so
+// nothing read past one marker is lost before the next readUntil.
+type terminal struct {
+	io.Writer
+	r *bufio.Reader
+}
+
+func spawnProcess(t *testing.T, name string, args ...string) (*terminal, *exec.Cmd) {
 	cmd := exec.Command(name, args...)
 	ptmx, err := pty.Start(cmd)
 	require.NoError(t, err, "starting %s", name)
-	return ptmx, cmd
+	return &terminal{Writer: ptmx, r: bufio.NewReader(ptmx)}, cmd
 }
 
 func sendLines(t *testing.T, tty io.Writer, lines ...string) {
@@ -27,15 +34,14 @@ func sendLines(t *testing.T, tty io.Writer, lines ...string) {
 	}
 }
 
-func readUntil(t *testing.T, tty io.Reader, marker string, timeout time.Duration) string {
-	r := bufio.NewReader(tty)
+func readUntil(t *testing.T, tty *terminal, marker string, timeout time.Duration) string {
 	deadline := time.Now().Add(timeout)
 	var out string
 	for {
 		if time.Now().After(deadline) {
 			t.Fatalf("timeout waiting for %q, got: %q", marker, out)
 		}
-		chunk, err := r.ReadString('\n')
+		chunk, err := tty.r.ReadString('\n')
 		require.NoError(t, err)
 		out += chunk
 		if strings.Contains(out, marker) {
@@ -46,20 +52,22 @@ func readUntil(t *testing.T, tty io.Reader, marker string, timeout time.Duration
 
 func Test_GameLog(t *testing.T) {
 	// 1) Start the server
-	serverTTY, serverCmd := spawnProcess(t, "go", "run", "./cmd/server/main.go")
+	serverTTY, serverCmd := spawnProcess(t, "go", "run", "./cmd/server/main.go", "-sessions", t.TempDir())
 	defer serverCmd.Process.Kill()
 	// Wait for it to connect to RabbitMQ
 	readUntil(t, serverTTY, "Connected to RabbitMQ", 5*time.Second)
 
 	// 2) Start client #1
-	cl1TTY, cl1Cmd := spawnProcess(t, "go", "run", "./cmd/client/main.go")
+	// Every client keeps its state in a fresh directory, so that unit IDs
+	// start at 1 however often the test runs.
+	cl1TTY, cl1Cmd := spawnProcess(t, "go", "run", "./cmd/client/main.go", "-data", t.TempDir())
 	defer cl1Cmd.Process.Kill()
 	// Wait for the username prompt
 	readUntil(t, cl1TTY, "Please enter your username:", 5*time.Second)
 	sendLines(t, cl1TTY, "napoleon", "spawn europe cavalry")
 
 	// 3) Start client #2
-	cl2TTY, cl2Cmd := spawnProcess(t, "go", "run", "./cmd/client/main.go")
+	cl2TTY, cl2Cmd := spawnProcess(t, "go", "run", "./cmd/client/main.go", "-data", t.TempDir())
 	defer cl2Cmd.Process.Kill()
 	readUntil(t, cl2TTY, "Please enter your username:", 5*time.Second)
 	sendLines(t, cl2TTY, "washington", "spawn americas infantry", "move europe 1")
@@ -69,18 +77,21 @@ func Test_GameLog(t *testing.T) {
 	t.Logf("cl2 output: %q", out)
 	require.Contains(t, out, "washington has declared war on napoleon")
 
-	srvout := readUntil(t, serverTTY, "Received Ack", 10*time.Second)
+	// The server also acks moves and syncs for its world model, so wait for
+	// the game log first and then for its ack.
+	srvout := readUntil(t, serverTTY, "received game log", 10*time.Second)
+	srvout += readUntil(t, serverTTY, "Received Ack", 10*time.Second)
 	t.Logf("server output: %q", srvout)
 	require.Contains(t, srvout, "received game log")
 }
 
 func Test_spam(t *testing.T) {
-	serverTTY, serverCmd := spawnProcess(t, "go", "run", "./cmd/server/main.go")
+	serverTTY, serverCmd := spawnProcess(t, "go", "run", "./cmd/server/main.go", "-sessions", t.TempDir())
 	defer serverCmd.Process.Kill()
 
 	readUntil(t, serverTTY, "Connected to RabbitMQ", 5*time.Second)
 
-	cl1TTY, cl1Cmd := spawnProcess(t, "go", "run", "./cmd/client/main.go")
+	cl1TTY, cl1Cmd := spawnProcess(t, "go", "run", "./cmd/client/main.go", "-data", t.TempDir())
 	defer cl1Cmd.Process.Kill()
 
 	readUntil(t, cl1TTY, "Please enter your username:", 5*time.Second)
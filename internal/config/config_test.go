@@ -0,0 +1,68 @@
+package config
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadPrecedence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "peril.json")
+	err := os.WriteFile(path, []byte(`{
+		"url": "amqp://file:5672/",
+		"vhost": "from-file",
+		"heartbeat": "30s",
+		"connection_name": "from-file",
+		"client_properties": {"team": "red"}
+	}`), 0644)
+	require.NoError(t, err)
+
+	t.Setenv(EnvConfigFile, path)
+	t.Setenv(EnvVHost, "from-env")
+	t.Setenv(EnvConnectionName, "from-env")
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	loader := NewLoader(fs, "peril-test")
+	require.NoError(t, fs.Parse([]string{"-connection-name", "from-flag", "-client-property", "seat=2"}))
+
+	cfg, err := loader.Load()
+	require.NoError(t, err)
+	require.Equal(t, "amqp://file:5672/", cfg.URL)
+	require.Equal(t, "from-env", cfg.VHost)
+	require.Equal(t, "from-flag", cfg.ConnectionName)
+	require.Equal(t, 30*time.Second, cfg.Heartbeat)
+	require.Equal(t, map[string]string{"team": "red", "seat": "2"}, cfg.ClientProperties)
+}
+
+func TestLoadReportsEveryProblem(t *testing.T) {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	loader := NewLoader(fs, "peril-test")
+	require.NoError(t, fs.Parse([]string{"-tls-cert", "missing.pem", "-heartbeat", "-1s"}))
+
+	_, err := loader.Load()
+	require.EqualError(t, err, `invalid configuration:
+  - TLS certificate file missing.pem is not readable: stat missing.pem: no such file or directory
+  - TLS client certificate and key must be set together
+  - TLS files are set but the broker url uses amqp://; use amqps://
+  - heartbeat must not be negative, got -1s`)
+}
+
+func TestLoadReportsFileProblemsWithTheRest(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "peril.json")
+	err := os.WriteFile(path, []byte(`{"heartbeat": "soon"}`), 0600)
+	require.NoError(t, err)
+	t.Setenv(EnvConfigFile, path)
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	loader := NewLoader(fs, "peril-test")
+	require.NoError(t, fs.Parse([]string{"-tls-key", "missing.pem"}))
+
+	_, err = loader.Load()
+	require.EqualError(t, err, `invalid configuration:
+  - TLS client certificate and key must be set together
+  - TLS key file missing.pem is not readable: stat missing.pem: no such file or directory
+  - config file `+path+`: heartbeat "soon" is not a duration such as 10s`)
+}
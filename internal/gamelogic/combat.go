@@ -0,0 +1,174 @@
+package gamelogic
+
+import "math/rand"
+
+// Battle is one war's fight in a single location.
+type Battle struct {
+	ID            string
+	Location      Location
+	Attacker      string
+	Defender      string
+	AttackerUnits []Unit
+	DefenderUnits []Unit
+}
+
+// BattleReport is how a battle went. An empty Winner is a draw.
+type BattleReport struct {
+	Terrain      string
+	DefenseBonus int
+	Rounds       []BattleRound
+	Winner       string
+	Casualties   map[string][]int
+}
+
+// BattleRound records the dice each side rolled, the damage they dealt and
+// the units that died as a result. Both sides strike at the same time.
+type BattleRound struct {
+	Number         int
+	AttackerRolls  []int
+	DefenderRolls  []int
+	AttackerDamage int
+	DefenderDamage int
+	Killed         map[string][]int
+}
+
+type CombatResolver interface {
+	Resolve(b Battle) BattleReport
+}
+
+// PowerResolver is the original rule: the side with more power wipes out
+// the other, and a tie wipes out both.
+type PowerResolver struct {
+	scenario *Scenario
+}
+
+func NewPowerResolver(sc *Scenario) *PowerResolver {
+	return &PowerResolver{scenario: sc}
+}
+
+func (r *PowerResolver) Resolve(b Battle) BattleReport {
+	report := BattleReport{
+		Terrain:    r.scenario.Terrain(b.Location),
+		Casualties: map[string][]int{},
+	}
+	attackerPower := r.scenario.PowerLevel(b.AttackerUnits)
+	defenderPower := r.scenario.PowerLevel(b.DefenderUnits)
+	switch {
+	case attackerPower > defenderPower:
+		report.Winner = b.Attacker
+		report.Casualties[b.Defender] = unitIDs(b.DefenderUnits)
+	case defenderPower > attackerPower:
+		report.Winner = b.Defender
+		report.Casualties[b.Attacker] = unitIDs(b.AttackerUnits)
+	default:
+		report.Casualties[b.Attacker] = unitIDs(b.AttackerUnits)
+		report.Casualties[b.Defender] = unitIDs(b.DefenderUnits)
+	}
+	return report
+}
+
+// DiceResolver is the default resolver. Every round each surviving unit
+// rolls a die and deals its power scaled by the roll; defenders add their
+// terrain and defender bonuses. Damage kills units in ID order, carrying
+// over to the next unit. The battle ends when a side is wiped out or after
+// the scenario's last round, when the defender holds the territory. Units
+// start every battle at full hit points. The dice come from the game's
+// combat stream for the battle's ID.
+type DiceResolver struct {
+	scenario *Scenario
+	rng      *RNG
+}
+
+func NewDiceResolver(sc *Scenario, rng *RNG) *DiceResolver {
+	return &DiceResolver{
+		scenario: sc,
+		rng:      rng,
+	}
+}
+
+type fighter struct {
+	unit Unit
+	hp   int
+}
+
+func (r *DiceResolver) Resolve(b Battle) BattleReport {
+	rng := r.rng.Stream("combat", b.ID)
+	report := BattleReport{
+		Terrain:      r.scenario.Terrain(b.Location),
+		DefenseBonus: r.scenario.DefenseBonus(b.Location),
+		Casualties:   map[string][]int{},
+	}
+	attackers := r.fighters(b.AttackerUnits)
+	defenders := r.fighters(b.DefenderUnits)
+
+	for n := 1; n <= r.scenario.Combat.Rounds && len(attackers) > 0 && len(defenders) > 0; n++ {
+		round := BattleRound{
+			Number: n,
+			Killed: map[string][]int{},
+		}
+		round.AttackerRolls, round.AttackerDamage = r.strike(rng, attackers, 0)
+		round.DefenderRolls, round.DefenderDamage = r.strike(rng, defenders, report.DefenseBonus)
+
+		var killed []int
+		attackers, killed = takeDamage(attackers, round.DefenderDamage)
+		if len(killed) > 0 {
+			round.Killed[b.Attacker] = killed
+			report.Casualties[b.Attacker] = append(report.Casualties[b.Attacker], killed...)
+		}
+		defenders, killed = takeDamage(defenders, round.AttackerDamage)
+		if len(killed) > 0 {
+			round.Killed[b.Defender] = killed
+			report.Casualties[b.Defender] = append(report.Casualties[b.Defender], killed...)
+		}
+		report.Rounds = append(report.Rounds, round)
+	}
+
+	switch {
+	case len(attackers) == 0 && len(defenders) == 0:
+	case len(defenders) == 0:
+		report.Winner = b.Attacker
+	default:
+		report.Winner = b.Defender
+	}
+	return report
+}
+
+func (r *DiceResolver) fighters(units []Unit) []fighter {
+	fighters := []fighter{}
+	for _, unit := range units {
+		u, _ := r.scenario.UnitType(unit.Rank)
+		fighters = append(fighters, fighter{unit: unit, hp: u.HP})
+	}
+	return fighters
+}
+
+// strike rolls for every fighter and returns the rolls and the total damage
+// they deal, rounded up per unit so that no unit with power does nothing.
+func (r *DiceResolver) strike(rng *rand.Rand, fighters []fighter, bonus int) ([]int, int) {
+	sides := r.scenario.Combat.Dice
+	rolls := []int{}
+	damage := 0
+	for _, f := range fighters {
+		roll := rng.Intn(sides) + 1
+		rolls = append(rolls, roll)
+		u, _ := r.scenario.UnitType(f.unit.Rank)
+		damage += (u.Power*roll + sides - 1) / sides
+	}
+	return rolls, damage * (100 + bonus) / 100
+}
+
+func takeDamage(fighters []fighter, damage int) ([]fighter, []int) {
+	survivors := []fighter{}
+	killed := []int{}
+	for _, f := range fighters {
+		hit := min(damage, f.hp)
+		f.hp -= hit
+		damage -= hit
+		if f.hp == 0 {
+			killed = append(killed, f.unit.ID)
+			continue
+		}
+		survivors = append(survivors, f)
+	}
+	return survivors, killed
+}
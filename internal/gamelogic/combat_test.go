@@ -0,0 +1,94 @@
+package gamelogic
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func testBattle(location Location, attackers, defenders []Unit) Battle {
+	return Battle{
+		ID:            "war-1",
+		Location:      location,
+		Attacker:      "washington",
+		Defender:      "napoleon",
+		AttackerUnits: attackers,
+		DefenderUnits: defenders,
+	}
+}
+
+func TestDiceResolverIsReproducible(t *testing.T) {
+	b := testBattle("europe",
+		[]Unit{{ID: 1, Rank: RankCavalry}, {ID: 2, Rank: RankInfantry}},
+		[]Unit{{ID: 1, Rank: RankCavalry}, {ID: 2, Rank: RankCavalry}},
+	)
+	first := NewDiceResolver(DefaultScenario(), NewRNG(42)).Resolve(b)
+	second := NewDiceResolver(DefaultScenario(), NewRNG(42)).Resolve(b)
+	require.Equal(t, first, second)
+	require.NotEmpty(t, first.Rounds)
+}
+
+func TestDiceResolverStopsWhenASideIsWipedOut(t *testing.T) {
+	b := testBattle("europe",
+		[]Unit{{ID: 1, Rank: RankArtillery}, {ID: 2, Rank: RankArtillery}},
+		[]Unit{{ID: 1, Rank: RankInfantry}},
+	)
+	report := NewDiceResolver(DefaultScenario(), NewRNG(1)).Resolve(b)
+	require.Len(t, report.Rounds, 1)
+	require.Equal(t, "washington", report.Winner)
+	require.Equal(t, []int{1}, report.Casualties["napoleon"])
+}
+
+func TestDiceResolverDefenderHoldsAfterTheLastRound(t *testing.T) {
+	sc, err := ParseScenario([]byte(`{
+		"name": "stalemate",
+		"territories": [{"name": "fort", "terrain": "walls"}],
+		"units": [{"rank": "infantry", "power": 1, "hp": 100}],
+		"terrains": [{"name": "walls", "defense": 100}],
+		"combat": {"dice": 6, "rounds": 2}
+	}`), ".json")
+	require.NoError(t, err)
+	b := testBattle("fort",
+		[]Unit{{ID: 1, Rank: RankInfantry}, {ID: 2, Rank: RankInfantry}},
+		[]Unit{{ID: 1, Rank: RankInfantry}},
+	)
+	report := NewDiceResolver(sc, NewRNG(7)).Resolve(b)
+	require.Len(t, report.Rounds, 2)
+	require.Equal(t, "napoleon", report.Winner)
+	require.Empty(t, report.Casualties)
+	require.Equal(t, 100, report.DefenseBonus)
+	for _, round := range report.Rounds {
+		require.Equal(t, 2, round.DefenderDamage)
+	}
+}
+
+func TestTakeDamageCarriesOverInIDOrder(t *testing.T) {
+	survivors, killed := takeDamage([]fighter{
+		{unit: Unit{ID: 1}, hp: 2},
+		{unit: Unit{ID: 2}, hp: 3},
+		{unit: Unit{ID: 3}, hp: 3},
+	}, 6)
+	require.Equal(t, []int{1, 2}, killed)
+	require.Equal(t, []fighter{{unit: Unit{ID: 3}, hp: 2}}, survivors)
+}
+
+func TestHandleWarAppliesPartialCasualties(t *testing.T) {
+	defender := newTestPlayer("napoleon",
+		Unit{ID: 1, Rank: RankInfantry, Location: "europe"},
+		Unit{ID: 2, Rank: RankCavalry, Location: "europe"},
+	)
+	res := WarResolution{
+		ID:            "war-1",
+		Attacker:      "washington",
+		Defender:      "napoleon",
+		Location:      "europe",
+		AttackerUnits: []Unit{{ID: 1, Rank: RankCavalry, Location: "europe"}},
+		DefenderUnits: []Unit{{ID: 1, Rank: RankInfantry, Location: "europe"}, {ID: 2, Rank: RankCavalry, Location: "europe"}},
+		Winner:        "napoleon",
+		Loser:         "washington",
+		Casualties:    map[string][]int{"washington": {1}, "napoleon": {1}},
+	}
+	outcome, _, _ := defender.HandleWar(res)
+	require.Equal(t, WarOutcomeYouWon, outcome)
+	require.Len(t, defender.GetPlayerSnap().Units, 1)
+}
@@ -0,0 +1,238 @@
+package gamelogic
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/bootdotdev/learn-pub-sub-starter/internal/routing"
+)
+
+// Alliances keeps track of who is allied with whom and of the proposals
+// nobody has answered yet. Clients and the server each keep one, fed by
+// the same diplomacy messages.
+type Alliances struct {
+	allied    map[[2]string]bool
+	proposals map[[2]string]bool
+	mu        *sync.RWMutex
+}
+
+func NewAlliances() *Alliances {
+	return &Alliances{
+		allied:    map[[2]string]bool{},
+		proposals: map[[2]string]bool{},
+		mu:        &sync.RWMutex{},
+	}
+}
+
+func pair(a, b string) [2]string {
+	if b < a {
+		a, b = b, a
+	}
+	return [2]string{a, b}
+}
+
+// Check reports why a diplomacy message would be refused, if it would.
+func (a *Alliances) Check(d routing.Diplomacy) error {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.check(d)
+}
+
+func (a *Alliances) check(d routing.Diplomacy) error {
+	if d.From == d.To {
+		return errors.New("you can not ally with yourself")
+	}
+	allied := a.allied[pair(d.From, d.To)]
+	switch d.Action {
+	case routing.DiplomacyPropose:
+		if allied {
+			return fmt.Errorf("%s and %s are already allies", d.From, d.To)
+		}
+	case routing.DiplomacyAccept:
+		if !a.proposals[[2]string{d.To, d.From}] {
+			return fmt.Errorf("%s has not proposed an alliance to %s", d.To, d.From)
+		}
+	case routing.DiplomacyBreak:
+		if !allied {
+			return fmt.Errorf("%s and %s are not allies", d.From, d.To)
+		}
+	default:
+		return fmt.Errorf("unknown diplomatic action %q", d.Action)
+	}
+	return nil
+}
+
+// Apply records a diplomacy message and reports whether it formed or broke
+// an alliance.
+func (a *Alliances) Apply(d routing.Diplomacy) (bool, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	err := a.check(d)
+	if err != nil {
+		return false, err
+	}
+	switch d.Action {
+	case routing.DiplomacyPropose:
+		a.proposals[[2]string{d.From, d.To}] = true
+		return false, nil
+	case routing.DiplomacyAccept:
+		delete(a.proposals, [2]string{d.To, d.From})
+		delete(a.proposals, [2]string{d.From, d.To})
+		a.allied[pair(d.From, d.To)] = true
+	case routing.DiplomacyBreak:
+		delete(a.allied, pair(d.From, d.To))
+	}
+	return true, nil
+}
+
+// AllianceSnapshot is an Alliances registry as saves and events keep it.
+// Proposals are listed proposer first.
+type AllianceSnapshot struct {
+	Allied    [][2]string `json:",omitempty"`
+	Proposals [][2]string `json:",omitempty"`
+}
+
+func (a *Alliances) Snapshot() AllianceSnapshot {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return AllianceSnapshot{
+		Allied:    sortedPairs(a.allied),
+		Proposals: sortedPairs(a.proposals),
+	}
+}
+
+func (a *Alliances) restore(s AllianceSnapshot) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.allied = map[[2]string]bool{}
+	for _, p := range s.Allied {
+		a.allied[pair(p[0], p[1])] = true
+	}
+	a.proposals = map[[2]string]bool{}
+	for _, p := range s.Proposals {
+		a.proposals[p] = true
+	}
+}
+
+func sortedPairs(pairs map[[2]string]bool) [][2]string {
+	sorted := [][2]string{}
+	for p := range pairs {
+		sorted = append(sorted, p)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i][0] != sorted[j][0] {
+			return sorted[i][0] < sorted[j][0]
+		}
+		return sorted[i][1] < sorted[j][1]
+	})
+	return sorted
+}
+
+func (a *Alliances) Allied(p1, p2 string) bool {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.allied[pair(p1, p2)]
+}
+
+func (a *Alliances) Allies(username string) []string {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	allies := []string{}
+	for p := range a.allied {
+		switch username {
+		case p[0]:
+			allies = append(allies, p[1])
+		case p[1]:
+			allies = append(allies, p[0])
+		}
+	}
+	sort.Strings(allies)
+	return allies
+}
+
+// DiplomacyLog is the game log entry for an alliance formed or broken.
+func DiplomacyLog(d routing.Diplomacy) routing.GameLog {
+	message := fmt.Sprintf("%s and %s formed an alliance", d.To, d.From)
+	if d.Action == routing.DiplomacyBreak {
+		message = fmt.Sprintf("%s broke the alliance with %s", d.From, d.To)
+	}
+	return routing.GameLog{
+		CurrentTime: d.At,
+		Message:     message,
+		Username:    d.From,
+	}
+}
+
+// PlanDiplomacy validates a propose, accept or break command and builds the
+// message to send to the other player.
+func (gs *GameState) PlanDiplomacy(words []string) (routing.Diplomacy, error) {
+	if len(words) < 2 {
+		return routing.Diplomacy{}, fmt.Errorf("usage: %s <player>", words[0])
+	}
+	err := routing.ValidateUsername(words[1])
+	if err != nil {
+		return routing.Diplomacy{}, err
+	}
+	d := routing.Diplomacy{
+		From:   gs.GetUsername(),
+		To:     words[1],
+		Action: words[0],
+		At:     time.Now(),
+	}
+	return d, gs.alliances.Check(d)
+}
+
+func describeDiplomacy(d routing.Diplomacy) string {
+	switch d.Action {
+	case routing.DiplomacyPropose:
+		return fmt.Sprintf("%s proposed an alliance to %s", d.From, d.To)
+	case routing.DiplomacyAccept:
+		return fmt.Sprintf("%s accepted an alliance with %s", d.From, d.To)
+	case routing.DiplomacyBreak:
+		return fmt.Sprintf("%s broke the alliance with %s", d.From, d.To)
+	}
+	return fmt.Sprintf("%s sent %s to %s", d.From, d.Action, d.To)
+}
+
+// HandleDiplomacy records a message the server accepted, either one another
+// player sent us or one of ours passed back to us, and reports whether it
+// printed anything. Our own messages only count once the server has passed
+// them back.
+func (gs *GameState) HandleDiplomacy(d routing.Diplomacy) bool {
+	username := gs.GetUsername()
+	if d.To != username && d.From != username {
+		return false
+	}
+	err := gs.alliances.Check(d)
+	if err != nil {
+		return false
+	}
+	gs.record(Event{Kind: EventAllianceChanged, Diplomacy: &d})
+	if d.From == username {
+		switch d.Action {
+		case routing.DiplomacyPropose:
+			fmt.Printf("\nYou proposed an alliance to %s.\n", d.To)
+		case routing.DiplomacyAccept:
+			fmt.Printf("\nYou are now allied with %s.\n", d.To)
+		case routing.DiplomacyBreak:
+			fmt.Printf("\nYou broke your alliance with %s.\n", d.To)
+		}
+		return true
+	}
+	switch d.Action {
+	case routing.DiplomacyPropose:
+		fmt.Printf("\n%s proposes an alliance. Type accept %s to accept it.\n", d.From, d.From)
+	case routing.DiplomacyAccept:
+		fmt.Printf("\n%s accepted your alliance.\n", d.From)
+	case routing.DiplomacyBreak:
+		fmt.Printf("\n%s broke their alliance with you.\n", d.From)
+	}
+	return true
+}
+
+func (gs *GameState) Allies() []string {
+	return gs.alliances.Allies(gs.GetUsername())
+}
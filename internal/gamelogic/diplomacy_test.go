@@ -0,0 +1,104 @@
+package gamelogic
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/bootdotdev/learn-pub-sub-starter/internal/routing"
+	"github.com/stretchr/testify/require"
+)
+
+func diplomacy(from, action, to string) routing.Diplomacy {
+	return routing.Diplomacy{From: from, To: to, Action: action}
+}
+
+func TestAlliancesNeedAProposal(t *testing.T) {
+	a := NewAlliances()
+	_, err := a.Apply(diplomacy("washington", routing.DiplomacyAccept, "napoleon"))
+	require.EqualError(t, err, "napoleon has not proposed an alliance to washington")
+
+	changed, err := a.Apply(diplomacy("napoleon", routing.DiplomacyPropose, "washington"))
+	require.NoError(t, err)
+	require.False(t, changed)
+	require.False(t, a.Allied("napoleon", "washington"))
+
+	changed, err = a.Apply(diplomacy("washington", routing.DiplomacyAccept, "napoleon"))
+	require.NoError(t, err)
+	require.True(t, changed)
+	require.True(t, a.Allied("napoleon", "washington"))
+	require.Equal(t, []string{"washington"}, a.Allies("napoleon"))
+	require.Error(t, a.Check(diplomacy("napoleon", routing.DiplomacyPropose, "washington")))
+
+	changed, err = a.Apply(diplomacy("napoleon", routing.DiplomacyBreak, "washington"))
+	require.NoError(t, err)
+	require.True(t, changed)
+	require.False(t, a.Allied("washington", "napoleon"))
+	_, err = a.Apply(diplomacy("washington", routing.DiplomacyAccept, "napoleon"))
+	require.Error(t, err, "a proposal is used up once accepted")
+}
+
+func TestAlliesSharingATerritoryDoNotFight(t *testing.T) {
+	gs := NewGameState("napoleon")
+	require.NoError(t, gs.CommandSpawn([]string{"spawn", "europe", "infantry"}))
+	d, err := gs.PlanDiplomacy([]string{"propose", "washington"})
+	require.NoError(t, err)
+	require.True(t, gs.HandleDiplomacy(d))
+	require.True(t, gs.HandleDiplomacy(diplomacy("washington", routing.DiplomacyAccept, "napoleon")))
+	require.Equal(t, []string{"washington"}, gs.Allies())
+
+	enemy := moveTo("washington", 0, Unit{ID: 1, Rank: RankInfantry}, "europe")
+	require.Equal(t, MoveOutComeSafe, gs.HandleMove(enemy))
+
+	require.True(t, gs.HandleDiplomacy(diplomacy("washington", routing.DiplomacyBreak, "napoleon")))
+	require.Equal(t, MoveOutcomeMakeWar, gs.HandleMove(enemy))
+}
+
+func TestAlliesAreNotAttackedAtTheEndOfATurn(t *testing.T) {
+	world := NewWorld(DefaultScenario())
+	world.EnableTurns()
+	syncUnits(t, world, "napoleon", Unit{ID: 1, Rank: RankArtillery, Location: "europe"})
+	syncUnits(t, world, "washington", Unit{ID: 1, Rank: RankInfantry, Location: "africa"})
+	_, err := world.Alliances().Apply(diplomacy("napoleon", routing.DiplomacyPropose, "washington"))
+	require.NoError(t, err)
+	_, err = world.Alliances().Apply(diplomacy("washington", routing.DiplomacyAccept, "napoleon"))
+	require.NoError(t, err)
+
+	ts := world.StartTurn(time.Now().Add(time.Minute))
+	_, err = world.HandleMove(moveTo("washington", ts.Turn, Unit{ID: 1, Rank: RankInfantry}, "europe"))
+	require.NoError(t, err)
+	result := world.EndTurn(NewPowerResolver(DefaultScenario()))
+	require.Empty(t, result.Wars)
+}
+
+func TestDiplomacyLog(t *testing.T) {
+	at := time.Date(2025, 4, 23, 12, 0, 0, 0, time.UTC)
+	d := routing.Diplomacy{From: "washington", To: "napoleon", Action: routing.DiplomacyAccept, At: at}
+	require.Equal(t, routing.GameLog{CurrentTime: at, Message: "napoleon and washington formed an alliance", Username: "washington"}, DiplomacyLog(d))
+	d.Action = routing.DiplomacyBreak
+	require.Equal(t, "washington broke the alliance with napoleon", DiplomacyLog(d).Message)
+}
+
+func TestAlliancesSurviveSavesAndReplays(t *testing.T) {
+	gs := NewGameState("napoleon")
+	require.True(t, gs.HandleDiplomacy(diplomacy("washington", routing.DiplomacyPropose, "napoleon")))
+	d, err := gs.PlanDiplomacy([]string{"accept", "washington"})
+	require.NoError(t, err)
+	require.Empty(t, gs.Allies(), "an acceptance counts once the server passes it back")
+	require.True(t, gs.HandleDiplomacy(d))
+	require.Equal(t, []string{"washington"}, gs.Allies())
+	require.True(t, gs.HandleDiplomacy(diplomacy("wellington", routing.DiplomacyPropose, "napoleon")))
+
+	require.Equal(t, []string{"washington"}, Replay("napoleon", gs.Events(), 0).Allies())
+	require.Empty(t, Replay("napoleon", gs.Events(), 1).Allies())
+
+	path := filepath.Join(t.TempDir(), "napoleon.save.json")
+	require.NoError(t, gs.Save(path))
+	save, err := LoadSave(path)
+	require.NoError(t, err)
+	restarted := NewGameState("napoleon")
+	require.NoError(t, restarted.Restore(save))
+	require.Equal(t, []string{"washington"}, restarted.Allies())
+	_, err = restarted.PlanDiplomacy([]string{"accept", "wellington"})
+	require.NoError(t, err, "unanswered proposals are saved too")
+}
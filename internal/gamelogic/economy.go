@@ -0,0 +1,94 @@
+package gamelogic
+
+import "fmt"
+
+// EconomyTick is what the server pays whenever it pays income. Balances are
+// authoritative and replace whatever the clients worked out locally. Each
+// player is only sent their own share, cut out with For.
+type EconomyTick struct {
+	Tick     int
+	Income   map[string]int
+	Balances map[string]int
+}
+
+// CollectIncome pays every player for each territory they control, that is
+// one where they have units and nobody else does.
+func (w *World) CollectIncome() EconomyTick {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.tick++
+
+	controlled := w.controlled()
+	tick := EconomyTick{
+		Tick:     w.tick,
+		Income:   map[string]int{},
+		Balances: map[string]int{},
+	}
+	for username, p := range w.players {
+		income := controlled[username] * w.scenario.Economy.Income
+		p.Gold += income
+		w.players[username] = p
+		tick.Income[username] = income
+		tick.Balances[username] = p.Gold
+	}
+	return tick
+}
+
+// For is the part of the tick one player may see.
+func (t EconomyTick) For(username string) EconomyTick {
+	own := EconomyTick{
+		Tick:     t.Tick,
+		Income:   map[string]int{},
+		Balances: map[string]int{},
+	}
+	if income, ok := t.Income[username]; ok {
+		own.Income[username] = income
+	}
+	if balance, ok := t.Balances[username]; ok {
+		own.Balances[username] = balance
+	}
+	return own
+}
+
+// controlled counts the territories each player holds alone.
+func (w *World) controlled() map[string]int {
+	holders := map[Location]map[string]struct{}{}
+	for username, p := range w.players {
+		for _, unit := range p.Units {
+			if holders[unit.Location] == nil {
+				holders[unit.Location] = map[string]struct{}{}
+			}
+			holders[unit.Location][username] = struct{}{}
+		}
+	}
+	counts := map[string]int{}
+	for _, h := range holders {
+		if len(h) != 1 {
+			continue
+		}
+		for username := range h {
+			counts[username]++
+		}
+	}
+	return counts
+}
+
+// HandleEconomyTick adopts the server's balance and returns what this
+// player earned.
+func (gs *GameState) HandleEconomyTick(t EconomyTick) int {
+	balance, ok := t.Balances[gs.GetUsername()]
+	if !ok {
+		return 0
+	}
+	gs.record(Event{
+		Kind:   EventGoldChanged,
+		Gold:   balance,
+		Reason: fmt.Sprintf("economic tick %d", t.Tick),
+	})
+	income := t.Income[gs.GetUsername()]
+	if income > 0 {
+		fmt.Println()
+		fmt.Printf("Your territories earned you %d gold; you now have %d.\n", income, balance)
+	}
+	return income
+}
@@ -0,0 +1,69 @@
+package gamelogic
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSpawnCostsGold(t *testing.T) {
+	gs := NewGameState("napoleon")
+	require.Equal(t, 10, gs.GetGold())
+	require.NoError(t, gs.CommandSpawn([]string{"spawn", "europe", "artillery"}))
+	require.NoError(t, gs.CommandSpawn([]string{"spawn", "europe", "artillery"}))
+	require.Equal(t, 0, gs.GetGold())
+
+	err := gs.CommandSpawn([]string{"spawn", "europe", "infantry"})
+	require.EqualError(t, err, "error: a(n) infantry costs 1 gold but you only have 0")
+}
+
+func TestWorldChargesNewUnitsAndRefusesWhatIsUnaffordable(t *testing.T) {
+	world := NewWorld(DefaultScenario())
+	delta, err := world.HandleSync(PlayerSync{Player: Player{
+		Username: "napoleon",
+		Gold:     1000,
+		Units: map[int]Unit{
+			1: {ID: 1, Owner: "napoleon", Rank: RankArtillery, Location: "europe"},
+			2: {ID: 2, Owner: "napoleon", Rank: RankCavalry, Location: "europe"},
+			3: {ID: 3, Owner: "napoleon", Rank: RankCavalry, Location: "europe"},
+		},
+	}})
+	require.NoError(t, err)
+	require.Equal(t, []int{3}, delta.Removed)
+
+	player, _ := world.GetPlayer("napoleon")
+	require.Equal(t, 2, player.Gold)
+	require.Len(t, player.Units, 2)
+}
+
+func TestCollectIncomeForControlledTerritories(t *testing.T) {
+	world := NewWorld(DefaultScenario())
+	_, err := world.HandleSync(PlayerSync{Player: Player{
+		Username: "napoleon",
+		Units: map[int]Unit{
+			1: {ID: 1, Owner: "napoleon", Rank: RankInfantry, Location: "europe"},
+			2: {ID: 2, Owner: "napoleon", Rank: RankInfantry, Location: "asia"},
+		},
+	}})
+	require.NoError(t, err)
+	_, err = world.HandleSync(PlayerSync{Player: Player{
+		Username: "washington",
+		Units: map[int]Unit{
+			1: {ID: 1, Owner: "washington", Rank: RankInfantry, Location: "asia"},
+		},
+	}})
+	require.NoError(t, err)
+
+	tick := world.CollectIncome()
+	require.Equal(t, 1, tick.Tick)
+	require.Equal(t, map[string]int{"napoleon": 1, "washington": 0}, tick.Income)
+	require.Equal(t, map[string]int{"napoleon": 9, "washington": 9}, tick.Balances)
+
+	own := tick.For("napoleon")
+	require.Equal(t, map[string]int{"napoleon": 9}, own.Balances, "nobody learns anyone else's gold")
+	require.Equal(t, map[string]int{"napoleon": 1}, own.Income)
+
+	gs := NewGameState("napoleon")
+	require.Equal(t, 1, gs.HandleEconomyTick(own))
+	require.Equal(t, 9, gs.GetGold())
+}
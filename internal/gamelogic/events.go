@@ -0,0 +1,275 @@
+package gamelogic
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bootdotdev/learn-pub-sub-starter/internal/routing"
+)
+
+const (
+	// A new client session starts from the army in Units, the balance in
+	// Gold and the alliances in Alliances; so does an army restored from a
+	// save.
+	EventSessionStarted = "session_started"
+	EventArmyRestored   = "army_restored"
+
+	EventUnitSpawned    = "unit_spawned"
+	EventUnitMoved      = "unit_moved"
+	EventUnitsDestroyed = "units_destroyed"
+	EventGamePaused     = "game_paused"
+	EventGoldChanged    = "gold_changed"
+
+	// Diplomacy we sent or received that changed our alliances.
+	EventAllianceChanged = "alliance_changed"
+
+	// An undo takes back the spawn or move numbered Undone: it removes the
+	// units in IDs and puts the units in Units back where they were.
+	EventUndone = "undone"
+)
+
+// Event is one change to a player's GameState. Every change is applied by
+// reduce, so replaying the events a player recorded rebuilds their state as
+// it was after any of them.
+type Event struct {
+	Seq    int
+	At     time.Time
+	Kind   string
+	Units  []Unit `json:",omitempty"`
+	IDs    []int  `json:",omitempty"`
+	Cost   int    `json:",omitempty"`
+	Gold   int    `json:",omitempty"`
+	Paused bool   `json:",omitempty"`
+	Reason string `json:",omitempty"`
+	Undone int    `json:",omitempty"`
+
+	Alliances *AllianceSnapshot  `json:",omitempty"`
+	Diplomacy *routing.Diplomacy `json:",omitempty"`
+}
+
+// reduce applies e to the state. The caller holds gs.mu.
+func (gs *GameState) reduce(e Event) {
+	switch e.Kind {
+	case EventSessionStarted, EventArmyRestored:
+		gs.Player.Units = map[int]Unit{}
+		for _, unit := range e.Units {
+			gs.Player.Units[unit.ID] = unit
+		}
+		gs.Player.Gold = e.Gold
+		if e.Alliances != nil {
+			gs.alliances.restore(*e.Alliances)
+		}
+	case EventUnitSpawned:
+		for _, unit := range e.Units {
+			gs.Player.Units[unit.ID] = unit
+		}
+		gs.Player.Gold -= e.Cost
+	case EventUnitMoved:
+		for _, unit := range e.Units {
+			gs.Player.Units[unit.ID] = unit
+		}
+	case EventUnitsDestroyed:
+		for _, id := range e.IDs {
+			delete(gs.Player.Units, id)
+		}
+	case EventGamePaused:
+		gs.Paused = e.Paused
+	case EventGoldChanged:
+		gs.Player.Gold = e.Gold
+	case EventAllianceChanged:
+		if e.Diplomacy != nil {
+			gs.alliances.Apply(*e.Diplomacy)
+		}
+	case EventUndone:
+		for _, id := range e.IDs {
+			delete(gs.Player.Units, id)
+		}
+		for _, unit := range e.Units {
+			if _, ok := gs.Player.Units[unit.ID]; ok {
+				gs.Player.Units[unit.ID] = unit
+			}
+		}
+	}
+}
+
+// record numbers e, applies it and appends it to the event log.
+func (gs *GameState) record(e Event) {
+	gs.mu.Lock()
+	defer gs.mu.Unlock()
+	gs.eventSeq++
+	e.Seq = gs.eventSeq
+	e.At = time.Now()
+	gs.reduce(e)
+	gs.events = append(gs.events, e)
+	if gs.eventLog == nil {
+		return
+	}
+	data, err := json.Marshal(e)
+	if err == nil {
+		_, err = gs.eventLog.Write(append(data, '\n'))
+	}
+	if err != nil {
+		log.Printf("could not write event %d to the event log: %v", e.Seq, err)
+	}
+}
+
+// RecordEvents appends every event from now on to the log at path, after
+// the events of earlier sessions, and starts a new session there. Call it
+// before anything else changes the state.
+func (gs *GameState) RecordEvents(path string) error {
+	previous, err := ReadEventLog(path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("could not open event log: %v", err)
+	}
+	gs.mu.Lock()
+	gs.eventLog = f
+	gs.events = previous
+	if len(previous) > 0 {
+		gs.eventSeq = previous[len(previous)-1].Seq
+	}
+	player := copyPlayer(gs.Player)
+	gs.mu.Unlock()
+	alliances := gs.alliances.Snapshot()
+	gs.record(Event{
+		Kind:      EventSessionStarted,
+		Units:     sortedUnits(player.Units),
+		Gold:      player.Gold,
+		Alliances: &alliances,
+	})
+	return nil
+}
+
+func (gs *GameState) CloseEventLog() error {
+	gs.mu.Lock()
+	defer gs.mu.Unlock()
+	if gs.eventLog == nil {
+		return nil
+	}
+	err := gs.eventLog.Close()
+	gs.eventLog = nil
+	return err
+}
+
+func ReadEventLog(path string) ([]Event, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	events := []Event{}
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		e := Event{}
+		err := json.Unmarshal(scanner.Bytes(), &e)
+		if err != nil {
+			return nil, fmt.Errorf("event log %s is corrupt at line %d: %v", path, line, err)
+		}
+		events = append(events, e)
+	}
+	return events, scanner.Err()
+}
+
+func (gs *GameState) Events() []Event {
+	gs.mu.RLock()
+	defer gs.mu.RUnlock()
+	return append([]Event{}, gs.events...)
+}
+
+// Replay rebuilds a player's state from their events, up to and including
+// the event numbered seq; a seq of 0 replays them all.
+func Replay(username string, events []Event, seq int) *GameState {
+	gs := NewGameState(username)
+	gs.mu.Lock()
+	defer gs.mu.Unlock()
+	for _, e := range events {
+		if seq > 0 && e.Seq > seq {
+			break
+		}
+		gs.reduce(e)
+		gs.events = append(gs.events, e)
+		gs.eventSeq = e.Seq
+	}
+	return gs
+}
+
+func sortedUnits(units map[int]Unit) []Unit {
+	sorted := []Unit{}
+	for _, unit := range units {
+		sorted = append(sorted, unit)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].ID < sorted[j].ID
+	})
+	return sorted
+}
+
+func (e Event) String() string {
+	var detail string
+	switch e.Kind {
+	case EventSessionStarted, EventArmyRestored:
+		detail = fmt.Sprintf("%d unit(s), %d gold", len(e.Units), e.Gold)
+	case EventUnitSpawned:
+		detail = fmt.Sprintf("%s for %d gold", formatUnits(e.Units), e.Cost)
+	case EventUnitMoved:
+		detail = formatUnits(e.Units)
+	case EventUnitsDestroyed:
+		detail = fmt.Sprintf("units %v", e.IDs)
+	case EventGamePaused:
+		detail = fmt.Sprintf("paused: %v", e.Paused)
+	case EventGoldChanged:
+		detail = fmt.Sprintf("%d gold", e.Gold)
+	case EventAllianceChanged:
+		if e.Diplomacy != nil {
+			detail = describeDiplomacy(*e.Diplomacy)
+		}
+	case EventUndone:
+		detail = fmt.Sprintf("took back #%d", e.Undone)
+	}
+	if e.Reason != "" {
+		detail += " (" + e.Reason + ")"
+	}
+	return fmt.Sprintf("#%d %s %s: %s", e.Seq, e.At.Local().Format(time.TimeOnly), e.Kind, detail)
+}
+
+func formatUnits(units []Unit) string {
+	parts := []string{}
+	for _, unit := range units {
+		parts = append(parts, fmt.Sprintf("%d %s in %s", unit.ID, unit.Rank, unit.Location))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// CommandHistory lists the events of this and earlier sessions, or with a
+// sequence number shows the army as it was right after that event.
+func (gs *GameState) CommandHistory(words []string) error {
+	events := gs.Events()
+	if len(words) < 2 {
+		for _, e := range events {
+			fmt.Println(e)
+		}
+		return nil
+	}
+	seq, err := strconv.Atoi(words[1])
+	if err != nil || seq < 1 {
+		return fmt.Errorf("error: %s is not an event number", words[1])
+	}
+	then := Replay(gs.GetUsername(), events, seq)
+	player := then.GetPlayerSnap()
+	fmt.Printf("After event #%d you had %d gold and %d unit(s):\n", seq, player.Gold, len(player.Units))
+	for _, unit := range sortedUnits(player.Units) {
+		fmt.Printf("* %v: %v, %v\n", unit.ID, unit.Location, unit.Rank)
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package gamelogic
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bootdotdev/learn-pub-sub-starter/internal/routing"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEveryChangeIsAnEvent(t *testing.T) {
+	gs := NewGameState("napoleon")
+	require.NoError(t, gs.CommandSpawn([]string{"spawn", "europe", "infantry"}))
+	require.NoError(t, gs.CommandSpawn([]string{"spawn", "europe", "cavalry"}))
+	_, err := gs.CommandMove([]string{"move", "asia", "1"})
+	require.NoError(t, err)
+	gs.HandleStateDelta(StateDelta{Username: "napoleon", Removed: []int{2}, Reason: "war war-1"})
+	gs.HandleEconomyTick(EconomyTick{Tick: 1, Income: map[string]int{"napoleon": 1}, Balances: map[string]int{"napoleon": 7}})
+	gs.HandlePause(routing.PlayingState{IsPaused: true})
+
+	kinds := []string{}
+	for _, e := range gs.Events() {
+		kinds = append(kinds, e.Kind)
+	}
+	require.Equal(t, []string{
+		EventUnitSpawned, EventUnitSpawned, EventUnitMoved, EventUnitsDestroyed, EventGoldChanged, EventGamePaused,
+	}, kinds)
+
+	replayed := Replay("napoleon", gs.Events(), 0)
+	require.Equal(t, gs.GetPlayerSnap(), replayed.GetPlayerSnap())
+	require.True(t, replayed.isPaused())
+
+	beforeWar := Replay("napoleon", gs.Events(), 3).GetPlayerSnap()
+	require.Len(t, beforeWar.Units, 2)
+	require.Equal(t, Location("asia"), beforeWar.Units[1].Location)
+	require.Equal(t, 6, beforeWar.Gold)
+}
+
+func TestEventLogSurvivesRestarts(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "napoleon.events.jsonl")
+	gs := NewGameState("napoleon")
+	require.NoError(t, gs.RecordEvents(path))
+	require.NoError(t, gs.CommandSpawn([]string{"spawn", "europe", "artillery"}))
+	require.NoError(t, gs.CloseEventLog())
+
+	restarted := NewGameState("napoleon")
+	require.NoError(t, restarted.RecordEvents(path))
+	events := restarted.Events()
+	require.Len(t, events, 3)
+	require.Equal(t, EventSessionStarted, events[2].Kind)
+	require.Equal(t, 3, events[2].Seq)
+	require.NoError(t, restarted.CloseEventLog())
+
+	logged, err := ReadEventLog(path)
+	require.NoError(t, err)
+	require.Equal(t, events[1].Units, logged[1].Units)
+	require.Len(t, Replay("napoleon", logged, 2).GetPlayerSnap().Units, 1)
+	require.Empty(t, Replay("napoleon", logged, 0).GetPlayerSnap().Units, "a new session starts over")
+}
+
+func TestReadEventLogReportsCorruption(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "napoleon.events.jsonl")
+	require.NoError(t, os.WriteFile(path, []byte("{\"Seq\":1,\"Kind\":\"unit_moved\"}\nnot json\n"), 0644))
+	_, err := ReadEventLog(path)
+	require.ErrorContains(t, err, "corrupt at line 2")
+}
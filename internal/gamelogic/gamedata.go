@@ -1,8 +1,11 @@
 package gamelogic
 
+import "fmt"
+
 type Player struct {
 	Username string
 	Units    map[int]Unit
+	Gold     int
 }
 
 type UnitRank string
@@ -15,38 +18,41 @@ const (
 
 type Unit struct {
 	ID       int
+	Owner    string
 	Rank     UnitRank
 	Location Location
 }
 
+// Key identifies a unit across all players; IDs alone are only unique per
+// player.
+func (u Unit) Key() string {
+	return fmt.Sprintf("%s#%d", u.Owner, u.ID)
+}
+
+// ArmyMove carries the turn it was ordered in; it is zero in real-time
+// games. Moves, like every message players send the server, carry the
+// sender's session Token.
 type ArmyMove struct {
 	Player     Player
 	Units      []Unit
 	ToLocation Location
+	Turn       int
+	Token      string `json:",omitempty"`
+}
+
+func (m ArmyMove) Sender() (string, string) {
+	return m.Player.Username, m.Token
 }
 
+// RecognitionOfWar is sent by the defender, who saw the attacker move in.
 type RecognitionOfWar struct {
 	Attacker Player
 	Defender Player
+	Token    string `json:",omitempty"`
 }
 
-type Location string
-
-func getAllRanks() map[UnitRank]struct{} {
-	return map[UnitRank]struct{}{
-		RankInfantry:  {},
-		RankCavalry:   {},
-		RankArtillery: {},
-	}
+func (rw RecognitionOfWar) Sender() (string, string) {
+	return rw.Defender.Username, rw.Token
 }
 
-func getAllLocations() map[Location]struct{} {
-	return map[Location]struct{}{
-		"americas":   {},
-		"europe":     {},
-		"africa":     {},
-		"asia":       {},
-		"australia":  {},
-		"antarctica": {},
-	}
-}
+type Location string
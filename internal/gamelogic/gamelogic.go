@@ -17,7 +17,19 @@ func PrintClientHelp() {
 	fmt.Println("* spawn <location> <rank>")
 	fmt.Println("    example:")
 	fmt.Println("    spawn europe infantry")
+	fmt.Println("* path <from> <to>")
+	fmt.Println("    example:")
+	fmt.Println("    path americas asia")
 	fmt.Println("* status")
+	fmt.Println("* propose <player>")
+	fmt.Println("    example:")
+	fmt.Println("    propose washington")
+	fmt.Println("* accept <player>")
+	fmt.Println("* break <player>")
+	fmt.Println("* history [event]")
+	fmt.Println("    example:")
+	fmt.Println("    history 3")
+	fmt.Println("* undo")
 	fmt.Println("* spam <n>")
 	fmt.Println("    example:")
 	fmt.Println("    spam 5")
@@ -40,8 +52,21 @@ func ClientWelcome() (string, error) {
 
 func PrintServerHelp() {
 	fmt.Println("Possible commands:")
+	fmt.Println("* games")
+	fmt.Println("* create <game>")
+	fmt.Println("* use <game>")
+	fmt.Println("    example:")
+	fmt.Println("    use default")
+	fmt.Println("* start")
+	fmt.Println("* end")
 	fmt.Println("* pause")
 	fmt.Println("* resume")
+	fmt.Println("* resume in <duration>")
+	fmt.Println("    example:")
+	fmt.Println("    resume in 60s")
+	fmt.Println("* cancel <id>")
+	fmt.Println("* world")
+	fmt.Println("* players")
 	fmt.Println("* quit")
 	fmt.Println("* help")
 }
@@ -58,7 +83,7 @@ func GetInput() []string {
 	return strings.Fields(line)
 }
 
-func GetMaliciousLog() string {
+func GetMaliciousLog(rng *rand.Rand) string {
 	possibleLogs := []string{
 		"Never interrupt your enemy when he is making a mistake.",
 		"The hardest thing of all for a soldier is to retreat.",
@@ -67,7 +92,7 @@ func GetMaliciousLog() string {
 		"The art of war is simple enough. Find out where your enemy is. Get at him as soon as you can. Strike him as hard as you can, and keep moving on.",
 		"All warfare is based on deception.",
 	}
-	randomIndex := rand.Intn(len(possibleLogs))
+	randomIndex := rng.Intn(len(possibleLogs))
 	msg := possibleLogs[randomIndex]
 	return msg
 }
@@ -83,10 +108,15 @@ func (gs *GameState) CommandStatus() {
 	} else {
 		fmt.Println("The game is not paused.")
 	}
+	gs.printTurn()
 
 	p := gs.GetPlayerSnap()
 	fmt.Printf("You are %s, and you have %d units.\n", p.Username, len(p.Units))
+	fmt.Printf("You have %d gold.\n", p.Gold)
 	for _, unit := range p.Units {
 		fmt.Printf("* %v: %v, %v\n", unit.ID, unit.Location, unit.Rank)
 	}
+	if allies := gs.Allies(); len(allies) > 0 {
+		fmt.Printf("You are allied with %s.\n", strings.Join(allies, ", "))
+	}
 }
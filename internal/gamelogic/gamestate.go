@@ -1,64 +1,96 @@
 package gamelogic
 
 import (
+	"fmt"
+	"math/rand"
+	"os"
 	"sync"
+
+	"github.com/bootdotdev/learn-pub-sub-starter/internal/routing"
 )
 
 type GameState struct {
-	Player Player
-	Paused bool
-	mu     *sync.RWMutex
+	Player     Player
+	Paused     bool
+	NextUnitID int
+	mu         *sync.RWMutex
+
+	scenario        *Scenario
+	unitCounterPath string
+	rng             *RNG
+	streams         map[string]int
+	turnBased       bool
+	turn            routing.TurnState
+	phase           string
+	events          []Event
+	eventSeq        int
+	eventLog        *os.File
+	alliances       *Alliances
+	saving          *sync.Mutex
 }
 
 func NewGameState(username string) *GameState {
-	return &GameState{
+	gs := &GameState{
 		Player: Player{
 			Username: username,
 			Units:    map[int]Unit{},
 		},
-		Paused: false,
-		mu:     &sync.RWMutex{},
+		Paused:     false,
+		NextUnitID: 1,
+		mu:         &sync.RWMutex{},
+		rng:        NewRNG(0),
+		streams:    map[string]int{},
+		phase:      routing.GamePhaseRunning,
+		alliances:  NewAlliances(),
+		saving:     &sync.Mutex{},
 	}
+	gs.UseScenario(DefaultScenario())
+	return gs
 }
 
-func (gs *GameState) resumeGame() {
-	gs.mu.Lock()
-	defer gs.mu.Unlock()
-	gs.Paused = false
-}
-
-func (gs *GameState) pauseGame() {
+// UseScenario switches to a scenario loaded from a file; it must be called
+// before any units are spawned.
+func (gs *GameState) UseScenario(sc *Scenario) {
 	gs.mu.Lock()
 	defer gs.mu.Unlock()
-	gs.Paused = true
+	gs.scenario = sc
+	gs.Player.Gold = sc.startingBalance()
 }
 
-func (gs *GameState) isPaused() bool {
+func (gs *GameState) Scenario() *Scenario {
 	gs.mu.RLock()
 	defer gs.mu.RUnlock()
-	return gs.Paused
+	return gs.scenario
 }
 
-func (gs *GameState) addUnit(u Unit) {
+// UseSeed adopts the game seed the server handed out when we joined.
+func (gs *GameState) UseSeed(seed int64) {
 	gs.mu.Lock()
 	defer gs.mu.Unlock()
-	gs.Player.Units[u.ID] = u
+	gs.rng = NewRNG(seed)
+	gs.streams = map[string]int{}
 }
 
-func (gs *GameState) removeUnitsInLocation(loc Location) {
+// NextStream returns a new random source for purpose. The n-th stream a
+// player asks for is the same every time the game is played with the same
+// seed.
+func (gs *GameState) NextStream(purpose string) *rand.Rand {
 	gs.mu.Lock()
 	defer gs.mu.Unlock()
-	for k, v := range gs.Player.Units {
-		if v.Location == loc {
-			delete(gs.Player.Units, k)
-		}
-	}
+	gs.streams[purpose]++
+	return gs.rng.Stream(purpose, fmt.Sprintf("%s#%d", gs.Player.Username, gs.streams[purpose]))
 }
 
-func (gs *GameState) UpdateUnit(u Unit) {
-	gs.mu.Lock()
-	defer gs.mu.Unlock()
-	gs.Player.Units[u.ID] = u
+func (gs *GameState) isPaused() bool {
+	gs.mu.RLock()
+	defer gs.mu.RUnlock()
+	return gs.Paused
+}
+
+func (gs *GameState) GetGold() int {
+	gs.mu.RLock()
+	defer gs.mu.RUnlock()
+	return gs.Player.Gold
 }
 
 func (gs *GameState) GetUsername() string {
@@ -92,5 +124,6 @@ func (gs *GameState) GetPlayerSnap() Player {
 	return Player{
 		Username: gs.Player.Username,
 		Units:    Units,
+		Gold:     gs.Player.Gold,
 	}
 }
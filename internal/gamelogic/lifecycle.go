@@ -0,0 +1,281 @@
+package gamelogic
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/bootdotdev/learn-pub-sub-starter/internal/routing"
+)
+
+// Points a controlled territory is worth in the standings, on top of the
+// power of a player's units.
+const territoryScore = 10
+
+// GameOver is broadcast once, when a victory condition is met or the server
+// ends the game.
+type GameOver struct {
+	Winner    string
+	Reason    string
+	Standings []Standing
+}
+
+type Standing struct {
+	Username    string
+	Territories int
+	Units       int
+	Gold        int
+	Score       int
+	Eliminated  bool
+}
+
+// Lifecycle moves the server's game from the lobby through play to its end
+// and decides when someone has won.
+type Lifecycle struct {
+	world   *World
+	victory Victory
+	phase   string
+	since   time.Time
+	played  time.Duration
+	mu      *sync.Mutex
+}
+
+func NewLifecycle(world *World) *Lifecycle {
+	return &Lifecycle{
+		world:   world,
+		victory: world.scenario.Victory,
+		phase:   routing.GamePhaseLobby,
+		mu:      &sync.Mutex{},
+	}
+}
+
+func (l *Lifecycle) Phase() string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.phase
+}
+
+func (l *Lifecycle) Start() error {
+	return l.move(routing.GamePhaseLobby, routing.GamePhaseRunning)
+}
+
+func (l *Lifecycle) Pause() error {
+	return l.move(routing.GamePhaseRunning, routing.GamePhasePaused)
+}
+
+func (l *Lifecycle) Resume() error {
+	return l.move(routing.GamePhasePaused, routing.GamePhaseRunning)
+}
+
+func (l *Lifecycle) move(from, to string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.phase != from {
+		return fmt.Errorf("the game is %s, not %s", l.phase, from)
+	}
+	l.phase = to
+	l.tally(time.Now())
+	return nil
+}
+
+// tally adds the time spent running since the last phase change.
+func (l *Lifecycle) tally(now time.Time) {
+	if !l.since.IsZero() {
+		l.played += now.Sub(l.since)
+		l.since = time.Time{}
+	}
+	if l.phase == routing.GamePhaseRunning {
+		l.since = now
+	}
+}
+
+// Played is how long the game has been running, not counting pauses.
+func (l *Lifecycle) Played(now time.Time) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.since.IsZero() {
+		return l.played
+	}
+	return l.played + now.Sub(l.since)
+}
+
+// CheckVictory ends a running game if a victory condition is met.
+func (l *Lifecycle) CheckVictory(now time.Time) (GameOver, bool) {
+	if l.Phase() != routing.GamePhaseRunning {
+		return GameOver{}, false
+	}
+	standings := l.world.Standings()
+	for _, s := range standings {
+		if l.victory.Territories > 0 && s.Territories >= l.victory.Territories {
+			return l.finish(s.Username, fmt.Sprintf("%s controls %d territories", s.Username, s.Territories), standings, now)
+		}
+	}
+	if l.victory.Elimination && len(standings) > 1 {
+		standing := []Standing{}
+		for _, s := range standings {
+			if !s.Eliminated {
+				standing = append(standing, s)
+			}
+		}
+		if len(standing) == 1 {
+			return l.finish(standing[0].Username, fmt.Sprintf("%s eliminated every opponent", standing[0].Username), standings, now)
+		}
+	}
+	limit := time.Duration(l.victory.TimeLimitSeconds) * time.Second
+	if limit > 0 && l.Played(now) >= limit && len(standings) > 0 {
+		winner := standings[0].Username
+		if len(standings) > 1 && standings[1].Score == standings[0].Score {
+			winner = ""
+		}
+		return l.finish(winner, fmt.Sprintf("the time limit of %v ran out", limit), standings, now)
+	}
+	return GameOver{}, false
+}
+
+// End finishes the game early, with the current leader as the winner.
+func (l *Lifecycle) End(reason string, now time.Time) (GameOver, bool) {
+	standings := l.world.Standings()
+	winner := ""
+	if len(standings) > 0 && (len(standings) == 1 || standings[0].Score > standings[1].Score) {
+		winner = standings[0].Username
+	}
+	return l.finish(winner, reason, standings, now)
+}
+
+func (l *Lifecycle) finish(winner, reason string, standings []Standing, now time.Time) (GameOver, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.phase == routing.GamePhaseFinished {
+		return GameOver{}, false
+	}
+	l.phase = routing.GamePhaseFinished
+	l.tally(now)
+	return GameOver{
+		Winner:    winner,
+		Reason:    reason,
+		Standings: standings,
+	}, true
+}
+
+// Standings ranks every player by score: territories they hold alone plus
+// the power of their units. Players without units who can not afford a new
+// one are eliminated.
+func (w *World) Standings() []Standing {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	controlled := w.controlled()
+	cheapest := -1
+	for _, u := range w.scenario.Units {
+		if cheapest == -1 || u.Cost < cheapest {
+			cheapest = u.Cost
+		}
+	}
+
+	standings := []Standing{}
+	for username, p := range w.players {
+		units := []Unit{}
+		for _, unit := range p.Units {
+			units = append(units, unit)
+		}
+		standings = append(standings, Standing{
+			Username:    username,
+			Territories: controlled[username],
+			Units:       len(units),
+			Gold:        p.Gold,
+			Score:       controlled[username]*territoryScore + w.scenario.PowerLevel(units),
+			Eliminated:  len(units) == 0 && p.Gold < cheapest,
+		})
+	}
+	sort.Slice(standings, func(i, j int) bool {
+		if standings[i].Score == standings[j].Score {
+			return standings[i].Username < standings[j].Username
+		}
+		return standings[i].Score > standings[j].Score
+	})
+	return standings
+}
+
+// GameLogs turns the result into game log entries, one per standing after
+// the announcement of the winner.
+func (g GameOver) GameLogs(now time.Time) []routing.GameLog {
+	message := fmt.Sprintf("The game ended in a draw: %s", g.Reason)
+	if g.Winner != "" {
+		message = fmt.Sprintf("%s won the game: %s", g.Winner, g.Reason)
+	}
+	logs := []routing.GameLog{{
+		CurrentTime: now,
+		Message:     message,
+		Username:    g.Winner,
+	}}
+	for i, s := range g.Standings {
+		logs = append(logs, routing.GameLog{
+			CurrentTime: now,
+			Message:     fmt.Sprintf("finished #%d with score %d (%d territories, %d units, %d gold)", i+1, s.Score, s.Territories, s.Units, s.Gold),
+			Username:    s.Username,
+		})
+	}
+	return logs
+}
+
+// UsePhase adopts the lifecycle phase the server reported when we joined.
+func (gs *GameState) UsePhase(phase string) {
+	gs.mu.Lock()
+	gs.phase = phase
+	gs.mu.Unlock()
+	gs.record(Event{
+		Kind:   EventGamePaused,
+		Paused: phase == routing.GamePhasePaused,
+		Reason: "joined a " + phase + " game",
+	})
+}
+
+// HandleGameStatus follows the server's lifecycle; orders are only accepted
+// while the game is running. Pauses are announced by HandlePause.
+func (gs *GameState) HandleGameStatus(status routing.GameStatus) {
+	gs.mu.Lock()
+	previous := gs.phase
+	gs.phase = status.Phase
+	gs.mu.Unlock()
+	switch {
+	case status.Phase == routing.GamePhaseLobby:
+		fmt.Println()
+		fmt.Println("The game is waiting in the lobby for the server to start it.")
+	case status.Phase == routing.GamePhaseRunning && previous == routing.GamePhaseLobby:
+		fmt.Println()
+		fmt.Println("The game has started!")
+	}
+}
+
+func (gs *GameState) HandleGameOver(g GameOver) {
+	defer fmt.Println("------------------------")
+	gs.mu.Lock()
+	gs.phase = routing.GamePhaseFinished
+	gs.mu.Unlock()
+	fmt.Println()
+	fmt.Println("==== Game Over ====")
+	if g.Winner == "" {
+		fmt.Printf("The game ended in a draw: %s.\n", g.Reason)
+	} else {
+		fmt.Printf("%s won the game: %s.\n", g.Winner, g.Reason)
+	}
+	for i, s := range g.Standings {
+		status := ""
+		if s.Eliminated {
+			status = " (eliminated)"
+		}
+		fmt.Printf("%d. %s: score %d, %d territories, %d units, %d gold%s\n", i+1, s.Username, s.Score, s.Territories, s.Units, s.Gold, status)
+	}
+}
+
+func (gs *GameState) checkPhase() error {
+	gs.mu.RLock()
+	defer gs.mu.RUnlock()
+	switch gs.phase {
+	case routing.GamePhaseLobby:
+		return fmt.Errorf("error: the game has not started yet")
+	case routing.GamePhaseFinished:
+		return fmt.Errorf("error: the game is over")
+	}
+	return nil
+}
@@ -0,0 +1,115 @@
+package gamelogic
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bootdotdev/learn-pub-sub-starter/internal/routing"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLifecycleTransitions(t *testing.T) {
+	lc := NewLifecycle(NewWorld(DefaultScenario()))
+	require.Equal(t, routing.GamePhaseLobby, lc.Phase())
+	require.Error(t, lc.Pause())
+
+	require.NoError(t, lc.Start())
+	require.Error(t, lc.Start())
+	require.NoError(t, lc.Pause())
+	require.Equal(t, routing.GamePhasePaused, lc.Phase())
+	require.NoError(t, lc.Resume())
+
+	_, over := lc.End("the server ended the game", time.Now())
+	require.True(t, over)
+	require.Equal(t, routing.GamePhaseFinished, lc.Phase())
+	_, over = lc.End("again", time.Now())
+	require.False(t, over)
+	require.Error(t, lc.Resume())
+}
+
+func TestTerritoryVictory(t *testing.T) {
+	world := NewWorld(DefaultScenario())
+	syncUnits(t, world, "napoleon",
+		Unit{ID: 1, Rank: RankInfantry, Location: "europe"},
+		Unit{ID: 2, Rank: RankInfantry, Location: "asia"},
+		Unit{ID: 3, Rank: RankInfantry, Location: "africa"},
+	)
+	syncUnits(t, world, "washington", Unit{ID: 1, Rank: RankInfantry, Location: "americas"})
+	lc := NewLifecycle(world)
+
+	_, over := lc.CheckVictory(time.Now())
+	require.False(t, over, "the lobby never ends on its own")
+	require.NoError(t, lc.Start())
+	_, over = lc.CheckVictory(time.Now())
+	require.False(t, over)
+
+	syncUnits(t, world, "napoleon",
+		Unit{ID: 1, Rank: RankInfantry, Location: "europe"},
+		Unit{ID: 2, Rank: RankInfantry, Location: "asia"},
+		Unit{ID: 3, Rank: RankInfantry, Location: "africa"},
+		Unit{ID: 4, Rank: RankInfantry, Location: "australia"},
+	)
+	g, over := lc.CheckVictory(time.Now())
+	require.True(t, over)
+	require.Equal(t, "napoleon", g.Winner)
+	require.Equal(t, "napoleon controls 4 territories", g.Reason)
+	require.Equal(t, routing.GamePhaseFinished, lc.Phase())
+}
+
+func TestEliminationVictory(t *testing.T) {
+	world := NewWorld(DefaultScenario())
+	syncUnits(t, world, "napoleon", Unit{ID: 1, Rank: RankInfantry, Location: "europe"})
+	syncUnits(t, world, "washington",
+		Unit{ID: 1, Rank: RankArtillery, Location: "americas"},
+		Unit{ID: 2, Rank: RankArtillery, Location: "americas"},
+	)
+	lc := NewLifecycle(world)
+	require.NoError(t, lc.Start())
+	_, over := lc.CheckVictory(time.Now())
+	require.False(t, over)
+
+	world.RemoveUnits("washington", []int{1, 2}, "killed in war")
+	g, over := lc.CheckVictory(time.Now())
+	require.True(t, over)
+	require.Equal(t, "napoleon", g.Winner)
+	require.Len(t, g.Standings, 2)
+	require.True(t, g.Standings[1].Eliminated)
+}
+
+func TestTimeLimitRanksStandings(t *testing.T) {
+	world := NewWorld(DefaultScenario())
+	syncUnits(t, world, "napoleon", Unit{ID: 1, Rank: RankCavalry, Location: "europe"})
+	syncUnits(t, world, "washington", Unit{ID: 1, Rank: RankInfantry, Location: "americas"})
+	lc := NewLifecycle(world)
+	lc.victory.TimeLimitSeconds = 60
+	require.NoError(t, lc.Start())
+	_, over := lc.CheckVictory(time.Now())
+	require.False(t, over)
+
+	g, over := lc.CheckVictory(time.Now().Add(time.Minute))
+	require.True(t, over)
+	require.Equal(t, "napoleon", g.Winner)
+	require.Equal(t, []Standing{
+		{Username: "napoleon", Territories: 1, Units: 1, Gold: 7, Score: 15},
+		{Username: "washington", Territories: 1, Units: 1, Gold: 9, Score: 11},
+	}, g.Standings)
+
+	logs := g.GameLogs(time.Now())
+	require.Len(t, logs, 3)
+	require.Equal(t, "napoleon won the game: the time limit of 1m0s ran out", logs[0].Message)
+	require.Equal(t, "washington", logs[2].Username)
+}
+
+func TestOrdersRefusedOutsideRunningGame(t *testing.T) {
+	gs := NewGameState("napoleon")
+	gs.UsePhase(routing.GamePhaseLobby)
+	err := gs.CommandSpawn([]string{"spawn", "europe", "infantry"})
+	require.EqualError(t, err, "error: the game has not started yet")
+
+	gs.HandleGameStatus(routing.GameStatus{Phase: routing.GamePhaseRunning})
+	require.NoError(t, gs.CommandSpawn([]string{"spawn", "europe", "infantry"}))
+
+	gs.HandleGameOver(GameOver{Winner: "washington", Reason: "washington eliminated every opponent"})
+	_, err = gs.PlanMove([]string{"move", "asia", "1"})
+	require.EqualError(t, err, "error: the game is over")
+}
@@ -0,0 +1,32 @@
+package gamelogic
+
+import (
+	"fmt"
+
+	"github.com/bootdotdev/learn-pub-sub-starter/internal/routing"
+)
+
+func PrintLobbyHelp() {
+	fmt.Println("You are in the lobby. Possible commands:")
+	fmt.Println("* games")
+	fmt.Println("* create <game>")
+	fmt.Println("* join <game>")
+	fmt.Println("    example:")
+	fmt.Println("    join default")
+	fmt.Println("* quit")
+	fmt.Println("* help")
+}
+
+func PrintGames(games []routing.GameSummary) {
+	if len(games) == 0 {
+		fmt.Println("No games are being hosted.")
+		return
+	}
+	for _, g := range games {
+		mode := "real time"
+		if g.TurnBased {
+			mode = "turns"
+		}
+		fmt.Printf("* %s: %s in %s, %s, %d player(s)\n", g.ID, g.ScenarioName, mode, g.Phase, g.Players)
+	}
+}
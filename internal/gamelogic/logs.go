@@ -9,15 +9,18 @@ import (
 	"github.com/bootdotdev/learn-pub-sub-starter/internal/routing"
 )
 
-const logsFile = "game.log"
-
 const writeToDiskSleep = 1 * time.Second
 
-func WriteLog(gamelog routing.GameLog) error {
+// LogFile is where a game's log is written: game-<game>.log.
+func LogFile(game string) string {
+	return "game-" + game + ".log"
+}
+
+func WriteLog(game string, gamelog routing.GameLog) error {
 	log.Printf("received game log...")
 	time.Sleep(writeToDiskSleep)
 
-	f, err := os.OpenFile(logsFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	f, err := os.OpenFile(LogFile(game), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
 	if err != nil {
 		return fmt.Errorf("could not open logs file: %v", err)
 	}
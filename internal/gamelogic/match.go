@@ -0,0 +1,271 @@
+package gamelogic
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bootdotdev/learn-pub-sub-starter/internal/routing"
+)
+
+const (
+	MatchStarted  = "match_started"
+	MatchSynced   = "player_synced"
+	MatchMoved    = "unit_moved"
+	MatchWar      = "war_fought"
+	MatchRemoved  = "units_removed"
+	MatchTurnEnd  = "turn_ended"
+	MatchGameLog  = "game_log"
+	MatchGameOver = "game_over"
+)
+
+// MatchEntry is one thing that happened in a match, as the server saw it.
+// Players holds the armies of everyone the entry changed, as they were
+// right after it, so a replay never has to re-run the rules.
+type MatchEntry struct {
+	Seq          int
+	At           time.Time
+	Kind         string
+	Turn         int              `json:",omitempty"`
+	Message      string           `json:",omitempty"`
+	ScenarioHash string           `json:",omitempty"`
+	Players      []Player         `json:",omitempty"`
+	War          *WarResolution   `json:",omitempty"`
+	Log          *routing.GameLog `json:",omitempty"`
+}
+
+// MatchRecorder writes a game's match log for cmd/replay.
+type MatchRecorder struct {
+	f     *os.File
+	world *World
+	seq   int
+	// closed drops entries recorded after the match ended, such as the
+	// outcome of a war fought as it ended.
+	closed bool
+	mu     *sync.Mutex
+}
+
+// NewMatchRecorder starts a match log at path for the game played in world.
+func NewMatchRecorder(path string, world *World, sc *Scenario, seed int64) (*MatchRecorder, error) {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("could not open match log: %v", err)
+	}
+	r := &MatchRecorder{
+		f:     f,
+		world: world,
+		mu:    &sync.Mutex{},
+	}
+	r.write(MatchEntry{
+		Kind:         MatchStarted,
+		Message:      fmt.Sprintf("%s with seed %d", sc.Name, seed),
+		ScenarioHash: sc.Hash(),
+	})
+	return r, nil
+}
+
+// Record logs a change to the armies of the given players.
+func (r *MatchRecorder) Record(kind, message string, usernames ...string) {
+	e := MatchEntry{
+		Kind:    kind,
+		Message: message,
+	}
+	for _, username := range usernames {
+		if p, ok := r.world.GetPlayer(username); ok {
+			e.Players = append(e.Players, p)
+		}
+	}
+	r.write(e)
+}
+
+func (r *MatchRecorder) RecordWar(res WarResolution) {
+	message := fmt.Sprintf("%s attacked %s in %s: %s won", res.Attacker, res.Defender, res.Location, res.Winner)
+	if res.IsDraw() {
+		message = fmt.Sprintf("%s attacked %s in %s: a draw", res.Attacker, res.Defender, res.Location)
+	}
+	e := MatchEntry{
+		Kind:    MatchWar,
+		Message: message,
+		War:     &res,
+	}
+	for _, username := range []string{res.Attacker, res.Defender} {
+		if p, ok := r.world.GetPlayer(username); ok {
+			e.Players = append(e.Players, p)
+		}
+	}
+	r.write(e)
+}
+
+// RecordTurn logs the end of a turn together with every army.
+func (r *MatchRecorder) RecordTurn(result TurnResult) {
+	r.write(MatchEntry{
+		Kind:    MatchTurnEnd,
+		Turn:    result.Turn,
+		Message: fmt.Sprintf("turn %d resolved: %d war(s) fought", result.Turn, len(result.Wars)),
+		Players: r.world.Snapshot(),
+	})
+}
+
+func (r *MatchRecorder) RecordLog(gl routing.GameLog) {
+	r.write(MatchEntry{
+		Kind:    MatchGameLog,
+		Message: gl.Message,
+		Log:     &gl,
+	})
+}
+
+func (r *MatchRecorder) RecordGameOver(over GameOver) {
+	r.write(MatchEntry{
+		Kind:    MatchGameOver,
+		Message: over.Reason,
+		Players: r.world.Snapshot(),
+	})
+}
+
+func (r *MatchRecorder) write(e MatchEntry) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.closed {
+		return
+	}
+	r.seq++
+	e.Seq = r.seq
+	e.At = time.Now()
+	if e.Turn == 0 {
+		e.Turn = r.world.Turn().Turn
+	}
+	data, err := json.Marshal(e)
+	if err == nil {
+		_, err = r.f.Write(append(data, '\n'))
+	}
+	if err != nil {
+		log.Printf("could not write entry %d to the match log: %v", e.Seq, err)
+	}
+}
+
+// Close ends the match log. Closing it again does nothing.
+func (r *MatchRecorder) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.closed {
+		return nil
+	}
+	r.closed = true
+	return r.f.Close()
+}
+
+func ReadMatch(path string) ([]MatchEntry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	entries := []MatchEntry{}
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		e := MatchEntry{}
+		err := json.Unmarshal(scanner.Bytes(), &e)
+		if err != nil {
+			return nil, fmt.Errorf("match log %s is corrupt at line %d: %v", path, line, err)
+		}
+		entries = append(entries, e)
+	}
+	return entries, scanner.Err()
+}
+
+// MatchSteps groups a match into the steps a replay shows: a turn at a time
+// in turn-based matches, and an entry at a time in real-time ones.
+func MatchSteps(entries []MatchEntry) [][]MatchEntry {
+	turnBased := false
+	for _, e := range entries {
+		if e.Kind == MatchTurnEnd {
+			turnBased = true
+		}
+	}
+	steps := [][]MatchEntry{}
+	step := []MatchEntry{}
+	for _, e := range entries {
+		step = append(step, e)
+		if !turnBased || e.Kind == MatchTurnEnd || e.Kind == MatchGameOver {
+			steps = append(steps, step)
+			step = []MatchEntry{}
+		}
+	}
+	if len(step) > 0 {
+		steps = append(steps, step)
+	}
+	return steps
+}
+
+// Board is every army in a match being replayed.
+type Board struct {
+	players map[string]Player
+	turn    int
+}
+
+func NewBoard() *Board {
+	return &Board{players: map[string]Player{}}
+}
+
+func (b *Board) Apply(e MatchEntry) {
+	if e.Kind == MatchStarted {
+		b.players = map[string]Player{}
+	}
+	for _, p := range e.Players {
+		b.players[p.Username] = copyPlayer(p)
+	}
+	b.turn = e.Turn
+}
+
+// Render draws the board as a table of territories, listing each player's
+// units in every territory they hold.
+func (b *Board) Render(w io.Writer, sc *Scenario) {
+	if b.turn > 0 {
+		fmt.Fprintf(w, "Turn %d\n", b.turn)
+	}
+	locations := []Location{}
+	for loc := range sc.Map().Locations() {
+		locations = append(locations, loc)
+	}
+	sort.Slice(locations, func(i, j int) bool {
+		return locations[i] < locations[j]
+	})
+	usernames := []string{}
+	for username := range b.players {
+		usernames = append(usernames, username)
+	}
+	sort.Strings(usernames)
+	for _, loc := range locations {
+		armies := []string{}
+		for _, username := range usernames {
+			counts := map[UnitRank]int{}
+			for _, unit := range b.players[username].Units {
+				if unit.Location == loc {
+					counts[unit.Rank]++
+				}
+			}
+			if len(counts) == 0 {
+				continue
+			}
+			ranks := []string{}
+			for rank, n := range counts {
+				ranks = append(ranks, fmt.Sprintf("%d %s", n, rank))
+			}
+			sort.Strings(ranks)
+			armies = append(armies, fmt.Sprintf("%s (%s)", username, strings.Join(ranks, ", ")))
+		}
+		held := "-"
+		if len(armies) > 0 {
+			held = strings.Join(armies, "; ")
+		}
+		fmt.Fprintf(w, "%-12s %s\n", loc, held)
+	}
+}
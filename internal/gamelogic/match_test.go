@@ -0,0 +1,68 @@
+package gamelogic
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMatchLogReplaysArmies(t *testing.T) {
+	sc := DefaultScenario()
+	world := NewWorld(sc)
+	path := filepath.Join(t.TempDir(), "default.jsonl")
+	r, err := NewMatchRecorder(path, world, sc, 42)
+	require.NoError(t, err)
+
+	syncUnits(t, world, "napoleon",
+		Unit{ID: 1, Rank: RankInfantry, Location: "europe"},
+		Unit{ID: 2, Rank: RankInfantry, Location: "europe"},
+	)
+	r.Record(MatchSynced, "napoleon has 2 unit(s)", "napoleon")
+	syncUnits(t, world, "washington", Unit{ID: 1, Rank: RankArtillery, Location: "americas"})
+	r.Record(MatchSynced, "washington has 1 unit(s)", "washington")
+	world.RemoveUnits("napoleon", []int{2}, "war war-1")
+	r.Record(MatchRemoved, "napoleon lost a unit", "napoleon")
+	require.NoError(t, r.Close())
+	require.NoError(t, r.Close(), "closing twice is harmless")
+	r.Record(MatchSynced, "napoleon came back", "napoleon")
+
+	entries, err := ReadMatch(path)
+	require.NoError(t, err)
+	require.Len(t, entries, 4)
+	require.Equal(t, MatchStarted, entries[0].Kind)
+	require.Equal(t, sc.Hash(), entries[0].ScenarioHash)
+
+	steps := MatchSteps(entries)
+	require.Len(t, steps, 4, "a real-time match is replayed an entry at a time")
+
+	board := NewBoard()
+	for _, e := range entries[:3] {
+		board.Apply(e)
+	}
+	out := &strings.Builder{}
+	board.Render(out, sc)
+	require.Contains(t, out.String(), "napoleon (2 infantry)")
+	require.Contains(t, out.String(), "washington (1 artillery)")
+
+	board.Apply(entries[3])
+	out.Reset()
+	board.Render(out, sc)
+	require.Contains(t, out.String(), "napoleon (1 infantry)")
+}
+
+func TestMatchStepsFollowTurns(t *testing.T) {
+	entries := []MatchEntry{
+		{Seq: 1, Kind: MatchStarted},
+		{Seq: 2, Kind: MatchSynced},
+		{Seq: 3, Kind: MatchWar},
+		{Seq: 4, Kind: MatchTurnEnd, Turn: 1},
+		{Seq: 5, Kind: MatchTurnEnd, Turn: 2},
+		{Seq: 6, Kind: MatchSynced},
+	}
+	steps := MatchSteps(entries)
+	require.Len(t, steps, 3)
+	require.Len(t, steps[0], 4)
+	require.Equal(t, 6, steps[2][0].Seq, "entries after the last turn still make a step")
+}
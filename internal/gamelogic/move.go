@@ -17,9 +17,16 @@ const (
 func (gs *GameState) HandleMove(move ArmyMove) MoveOutcome {
 	defer fmt.Println("------------------------")
 	player := gs.GetPlayerSnap()
+	if player.Username != move.Player.Username {
+		move, _ = RedactMove(move, gs.Scenario().Map().VisibleLocations(player))
+	}
 
 	fmt.Println()
 	fmt.Println("==== Move Detected ====")
+	if len(move.Units) == 0 {
+		fmt.Println("Units are moving somewhere you can not see.")
+		return MoveOutComeSafe
+	}
 	fmt.Printf("%s is moving %v unit(s) to %s\n", move.Player.Username, len(move.Units), move.ToLocation)
 	for _, unit := range move.Units {
 		fmt.Printf("* %v\n", unit.Rank)
@@ -30,6 +37,14 @@ func (gs *GameState) HandleMove(move ArmyMove) MoveOutcome {
 	}
 
 	overlappingLocation := getOverlappingLocation(player, move.Player)
+	if overlappingLocation != "" && gs.alliances.Allied(player.Username, move.Player.Username) {
+		fmt.Printf("You share %s with your ally %s.\n", overlappingLocation, move.Player.Username)
+		return MoveOutComeSafe
+	}
+	if overlappingLocation != "" && gs.TurnBased() {
+		fmt.Printf("You have units in %s! The war with %s will be fought when the turn ends.\n", overlappingLocation, move.Player.Username)
+		return MoveOutComeSafe
+	}
 	if overlappingLocation != "" {
 		fmt.Printf("You have units in %s! You are at war with %s!\n", overlappingLocation, move.Player.Username)
 		return MoveOutcomeMakeWar
@@ -38,26 +53,48 @@ func (gs *GameState) HandleMove(move ArmyMove) MoveOutcome {
 	return MoveOutComeSafe
 }
 
+// getOverlappingLocation picks the first location, by name, where both
+// players have units, so that every client and the server agree on it.
 func getOverlappingLocation(p1 Player, p2 Player) Location {
+	locations := map[Location]struct{}{}
 	for _, u1 := range p1.Units {
-		for _, u2 := range p2.Units {
-			if u1.Location == u2.Location {
-				return u1.Location
-			}
+		locations[u1.Location] = struct{}{}
+	}
+	var overlap Location
+	for _, u2 := range p2.Units {
+		if _, ok := locations[u2.Location]; ok && (overlap == "" || u2.Location < overlap) {
+			overlap = u2.Location
 		}
 	}
-	return ""
+	return overlap
 }
 
 func (gs *GameState) CommandMove(words []string) (ArmyMove, error) {
+	mv, err := gs.PlanMove(words)
+	if err != nil {
+		return ArmyMove{}, err
+	}
+	gs.ApplyMove(mv)
+	return mv, nil
+}
+
+// PlanMove validates a move command and builds the resulting ArmyMove
+// without touching the game state, so the caller can record the move
+// before applying it.
+func (gs *GameState) PlanMove(words []string) (ArmyMove, error) {
 	if gs.isPaused() {
 		return ArmyMove{}, errors.New("the game is paused, you can not move units")
 	}
+	turn, err := gs.checkOrderWindow()
+	if err != nil {
+		return ArmyMove{}, err
+	}
 	if len(words) < 3 {
 		return ArmyMove{}, errors.New("usage: move <location> <unitID> <unitID> <unitID> etc")
 	}
 	newLocation := Location(words[1])
-	locations := getAllLocations()
+	sc := gs.Scenario()
+	locations := sc.Map().Locations()
 	if _, ok := locations[newLocation]; !ok {
 		return ArmyMove{}, fmt.Errorf("error: %s is not a valid location", newLocation)
 	}
@@ -71,22 +108,56 @@ func (gs *GameState) CommandMove(words []string) (ArmyMove, error) {
 		unitIDs = append(unitIDs, unitID)
 	}
 
+	player := gs.GetPlayerSnap()
 	newUnits := []Unit{}
 	for _, unitID := range unitIDs {
-		unit, ok := gs.GetUnit(unitID)
+		unit, ok := player.Units[unitID]
 		if !ok {
 			return ArmyMove{}, fmt.Errorf("error: unit with ID %v not found", unitID)
 		}
+		err := sc.checkMove(unit, newLocation)
+		if err != nil {
+			return ArmyMove{}, err
+		}
 		unit.Location = newLocation
-		gs.UpdateUnit(unit)
+		player.Units[unitID] = unit
 		newUnits = append(newUnits, unit)
 	}
 
-	mv := ArmyMove{
+	return ArmyMove{
 		ToLocation: newLocation,
 		Units:      newUnits,
-		Player:     gs.GetPlayerSnap(),
-	}
+		Player:     player,
+		Turn:       turn,
+	}, nil
+}
+
+func (gs *GameState) ApplyMove(mv ArmyMove) {
+	gs.record(Event{
+		Kind:   EventUnitMoved,
+		Units:  mv.Units,
+		Reason: fmt.Sprintf("moved to %s", mv.ToLocation),
+	})
 	fmt.Printf("Moved %v units to %s\n", len(mv.Units), mv.ToLocation)
-	return mv, nil
+}
+
+func (gs *GameState) CommandPath(words []string) error {
+	if len(words) < 3 {
+		return errors.New("usage: path <from> <to>")
+	}
+	from, to := Location(words[1]), Location(words[2])
+	sc := gs.Scenario()
+	path, cost, err := sc.Map().ShortestPath(from, to)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("%s (cost %d)\n", formatPath(path), cost)
+	for _, u := range sc.Units {
+		if u.Speed >= cost {
+			fmt.Printf("* %s can make it in one move\n", u.Rank)
+		} else {
+			fmt.Printf("* %s can not make it in one move (speed %d)\n", u.Rank, u.Speed)
+		}
+	}
+	return nil
 }
@@ -11,9 +11,8 @@ func (gs *GameState) HandlePause(ps routing.PlayingState) {
 	fmt.Println()
 	if ps.IsPaused {
 		fmt.Println("==== Pause Detected ====")
-		gs.pauseGame()
 	} else {
 		fmt.Println("==== Resume Detected ====")
-		gs.resumeGame()
 	}
+	gs.record(Event{Kind: EventGamePaused, Paused: ps.IsPaused})
 }
@@ -0,0 +1,172 @@
+package gamelogic
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/bootdotdev/learn-pub-sub-starter/internal/routing"
+)
+
+type Presence struct {
+	Username string
+	Status   string
+	LastSeen time.Time
+}
+
+// Roster is the server's view of who is playing, built from heartbeats.
+// Players who stop sending them go AFK after afkAfter and, if eliminateAfter
+// is set, are eliminated once they have been silent that long while the game
+// was running. Players who said they were leaving are never eliminated.
+type Roster struct {
+	afkAfter       time.Duration
+	eliminateAfter time.Duration
+	players        map[string]*Presence
+	// away is how long each player has been silent while the game ran.
+	away    map[string]time.Duration
+	checked time.Time
+	mu      *sync.Mutex
+}
+
+func NewRoster(afkAfter, eliminateAfter time.Duration) *Roster {
+	return &Roster{
+		afkAfter:       afkAfter,
+		eliminateAfter: eliminateAfter,
+		players:        map[string]*Presence{},
+		away:           map[string]time.Duration{},
+		mu:             &sync.Mutex{},
+	}
+}
+
+// Heartbeat records that a player was seen at now and returns the event to
+// announce, if any: a player joining, coming back or leaving. Eliminated
+// players stay eliminated.
+func (r *Roster) Heartbeat(hb routing.Heartbeat, now time.Time) (routing.PresenceEvent, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	p, ok := r.players[hb.Username]
+	if !ok {
+		p = &Presence{Username: hb.Username}
+		r.players[hb.Username] = p
+	}
+	if p.Status == routing.PresenceEliminated {
+		return routing.PresenceEvent{}, false
+	}
+	p.LastSeen = now
+	r.away[hb.Username] = 0
+	status := routing.PresenceOnline
+	if hb.Leaving {
+		status = routing.PresenceLeft
+	}
+	if p.Status == status {
+		return routing.PresenceEvent{}, false
+	}
+	p.Status = status
+	return routing.PresenceEvent{Username: p.Username, Status: status, At: now}, true
+}
+
+// Check marks silent players AFK or eliminated and returns the changes, by
+// username. Only time that passes while running counts towards elimination,
+// so players are not eliminated for waiting in the lobby or out a pause.
+func (r *Roster) Check(now time.Time, running bool) []routing.PresenceEvent {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	sinceCheck := now.Sub(r.checked)
+	r.checked = now
+	events := []routing.PresenceEvent{}
+	for _, p := range r.players {
+		silent := now.Sub(p.LastSeen)
+		if running {
+			r.away[p.Username] += min(sinceCheck, silent)
+		}
+		status := p.Status
+		switch {
+		case p.Status == routing.PresenceEliminated, p.Status == routing.PresenceLeft:
+			continue
+		case r.eliminateAfter > 0 && r.away[p.Username] >= r.eliminateAfter:
+			status = routing.PresenceEliminated
+		case p.Status == routing.PresenceOnline && silent >= r.afkAfter:
+			status = routing.PresenceAFK
+		}
+		if status == p.Status {
+			continue
+		}
+		p.Status = status
+		events = append(events, routing.PresenceEvent{Username: p.Username, Status: status, At: now})
+	}
+	sort.Slice(events, func(i, j int) bool {
+		return events[i].Username < events[j].Username
+	})
+	return events
+}
+
+func (r *Roster) List() []Presence {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	players := []Presence{}
+	for _, p := range r.players {
+		players = append(players, *p)
+	}
+	sort.Slice(players, func(i, j int) bool {
+		return players[i].Username < players[j].Username
+	})
+	return players
+}
+
+func (r *Roster) PrintPlayers(now time.Time) {
+	players := r.List()
+	if len(players) == 0 {
+		fmt.Println("No players have joined yet.")
+		return
+	}
+	for _, p := range players {
+		fmt.Printf("* %s: %s, last seen %v ago\n", p.Username, p.Status, now.Sub(p.LastSeen).Round(time.Second))
+	}
+}
+
+// Forfeit eliminates a player who stopped playing: their units are destroyed
+// and their gold is gone, so they can not spawn new ones.
+func (w *World) Forfeit(username, reason string) StateDelta {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	p, ok := w.players[username]
+	if !ok {
+		return StateDelta{}
+	}
+	if w.destroyed[username] == nil {
+		w.destroyed[username] = map[int]struct{}{}
+	}
+	ids := []int{}
+	for id := range p.Units {
+		ids = append(ids, id)
+		w.destroyed[username][id] = struct{}{}
+		delete(p.Units, id)
+	}
+	sort.Ints(ids)
+	p.Gold = 0
+	w.players[username] = p
+	return w.delta(username, nil, ids, reason)
+}
+
+// HandlePresence tells the player about others coming and going, and
+// reports whether it printed anything.
+func (gs *GameState) HandlePresence(e routing.PresenceEvent) bool {
+	if e.Username == gs.GetUsername() && e.Status == routing.PresenceOnline {
+		return false
+	}
+	username := e.Username
+	switch e.Status {
+	case routing.PresenceOnline:
+		fmt.Printf("\n%s joined the game.\n", username)
+	case routing.PresenceAFK:
+		fmt.Printf("\n%s went quiet and is AFK.\n", username)
+	case routing.PresenceLeft:
+		fmt.Printf("\n%s left the game.\n", username)
+	case routing.PresenceEliminated:
+		fmt.Printf("\n%s stayed away too long and was eliminated.\n", username)
+	default:
+		return false
+	}
+	return true
+}
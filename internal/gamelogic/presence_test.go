@@ -0,0 +1,84 @@
+package gamelogic
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bootdotdev/learn-pub-sub-starter/internal/routing"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRosterFollowsHeartbeats(t *testing.T) {
+	r := NewRoster(30*time.Second, 5*time.Minute)
+	start := time.Date(2025, 4, 23, 12, 0, 0, 0, time.UTC)
+
+	e, ok := r.Heartbeat(routing.Heartbeat{Username: "napoleon"}, start)
+	require.True(t, ok)
+	require.Equal(t, routing.PresenceOnline, e.Status)
+	_, ok = r.Heartbeat(routing.Heartbeat{Username: "napoleon"}, start.Add(5*time.Second))
+	require.False(t, ok, "a player who is already online is not announced again")
+
+	require.Empty(t, r.Check(start.Add(30*time.Second), true))
+	events := r.Check(start.Add(35*time.Second), true)
+	require.Equal(t, []routing.PresenceEvent{{Username: "napoleon", Status: routing.PresenceAFK, At: start.Add(35 * time.Second)}}, events)
+
+	e, ok = r.Heartbeat(routing.Heartbeat{Username: "napoleon"}, start.Add(time.Minute))
+	require.True(t, ok)
+	require.Equal(t, routing.PresenceOnline, e.Status)
+
+	e, ok = r.Heartbeat(routing.Heartbeat{Username: "napoleon", Leaving: true}, start.Add(2*time.Minute))
+	require.True(t, ok)
+	require.Equal(t, routing.PresenceLeft, e.Status)
+	require.Empty(t, r.Check(start.Add(3*time.Minute), true), "players who left are not AFK")
+}
+
+func TestRosterEliminatesSilentPlayers(t *testing.T) {
+	r := NewRoster(30*time.Second, 5*time.Minute)
+	start := time.Date(2025, 4, 23, 12, 0, 0, 0, time.UTC)
+	r.Heartbeat(routing.Heartbeat{Username: "napoleon"}, start)
+	r.Heartbeat(routing.Heartbeat{Username: "washington"}, start.Add(4*time.Minute))
+
+	events := r.Check(start.Add(5*time.Minute), true)
+	require.Equal(t, []routing.PresenceEvent{
+		{Username: "napoleon", Status: routing.PresenceEliminated, At: start.Add(5 * time.Minute)},
+		{Username: "washington", Status: routing.PresenceAFK, At: start.Add(5 * time.Minute)},
+	}, events)
+
+	_, ok := r.Heartbeat(routing.Heartbeat{Username: "napoleon"}, start.Add(6*time.Minute))
+	require.False(t, ok, "eliminated players can not come back")
+	require.Equal(t, []Presence{
+		{Username: "napoleon", Status: routing.PresenceEliminated, LastSeen: start},
+		{Username: "washington", Status: routing.PresenceAFK, LastSeen: start.Add(4 * time.Minute)},
+	}, r.List())
+}
+
+func TestRosterOnlyEliminatesWhileTheGameRuns(t *testing.T) {
+	r := NewRoster(30*time.Second, 5*time.Minute)
+	start := time.Date(2025, 4, 23, 12, 0, 0, 0, time.UTC)
+	r.Heartbeat(routing.Heartbeat{Username: "napoleon"}, start)
+	r.Heartbeat(routing.Heartbeat{Username: "washington"}, start)
+	r.Heartbeat(routing.Heartbeat{Username: "washington", Leaving: true}, start)
+
+	events := r.Check(start.Add(time.Hour), false)
+	require.Equal(t, []routing.PresenceEvent{{Username: "napoleon", Status: routing.PresenceAFK, At: start.Add(time.Hour)}}, events, "paused games only mark players AFK")
+	require.Empty(t, r.Check(start.Add(time.Hour+4*time.Minute), true))
+	events = r.Check(start.Add(time.Hour+5*time.Minute), true)
+	require.Equal(t, []routing.PresenceEvent{{Username: "napoleon", Status: routing.PresenceEliminated, At: start.Add(time.Hour + 5*time.Minute)}}, events, "players who left are not eliminated")
+}
+
+func TestForfeitRemovesUnitsAndGold(t *testing.T) {
+	world := NewWorld(DefaultScenario())
+	syncUnits(t, world, "napoleon",
+		Unit{ID: 1, Rank: RankInfantry, Location: "europe"},
+		Unit{ID: 2, Rank: RankInfantry, Location: "asia"},
+	)
+	delta := world.Forfeit("napoleon", "eliminated for staying away")
+	require.Equal(t, []int{1, 2}, delta.Removed)
+
+	player, _ := world.GetPlayer("napoleon")
+	require.Empty(t, player.Units)
+	require.Zero(t, player.Gold)
+	standings := world.Standings()
+	require.True(t, standings[0].Eliminated)
+	require.True(t, world.Forfeit("washington", "").IsEmpty())
+}
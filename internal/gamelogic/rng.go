@@ -0,0 +1,34 @@
+package gamelogic
+
+import (
+	"encoding/binary"
+	"hash/fnv"
+	"math/rand"
+)
+
+// RNG derives every random decision in a game from the seed the server
+// picks. Each decision draws from its own stream, named by what it is for
+// and which event it belongs to, so the outcome only depends on the seed and
+// the order of messages, not on how many numbers other code drew before.
+type RNG struct {
+	seed int64
+}
+
+func NewRNG(seed int64) *RNG {
+	return &RNG{seed: seed}
+}
+
+func (r *RNG) Seed() int64 {
+	return r.seed
+}
+
+// Stream returns the random source for one purpose, such as "combat", and
+// one event, such as a war ID.
+func (r *RNG) Stream(purpose, event string) *rand.Rand {
+	h := fnv.New64a()
+	binary.Write(h, binary.BigEndian, r.seed)
+	h.Write([]byte(purpose))
+	h.Write([]byte{0})
+	h.Write([]byte(event))
+	return rand.New(rand.NewSource(int64(h.Sum64())))
+}
@@ -0,0 +1,35 @@
+package gamelogic
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRNGStreamsAreReproducibleAndIndependent(t *testing.T) {
+	a := NewRNG(42).Stream("combat", "war-1")
+	b := NewRNG(42).Stream("combat", "war-1")
+	require.Equal(t, a.Int63(), b.Int63())
+
+	c := NewRNG(42).Stream("combat", "war-2")
+	d := NewRNG(43).Stream("combat", "war-1")
+	first := NewRNG(42).Stream("combat", "war-1").Int63()
+	require.NotEqual(t, first, c.Int63())
+	require.NotEqual(t, first, d.Int63())
+}
+
+func TestSpamIsReproducibleForASeed(t *testing.T) {
+	play := func() []string {
+		gs := NewGameState("napoleon")
+		gs.UseSeed(7)
+		logs := []string{}
+		for range 2 {
+			rng := gs.NextStream("spam")
+			for range 5 {
+				logs = append(logs, GetMaliciousLog(rng))
+			}
+		}
+		return logs
+	}
+	require.Equal(t, play(), play())
+}
@@ -0,0 +1,106 @@
+package gamelogic
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Bump saveVersion whenever SaveFile changes shape, and teach LoadSave to
+// read the previous version. Version 1 saves have no alliances.
+const saveVersion = 2
+
+// SaveFile is a player's army as the client keeps it on disk between
+// sessions.
+type SaveFile struct {
+	Version      int
+	SavedAt      time.Time
+	ScenarioHash string
+	Player       Player
+	NextUnitID   int
+	Alliances    AllianceSnapshot
+}
+
+// Save writes the player's army to path, replacing the previous save only
+// once the new one is complete. Saves run one at a time, so an autosave and
+// a quit can not overwrite each other's files or a newer save.
+func (gs *GameState) Save(path string) error {
+	gs.saving.Lock()
+	defer gs.saving.Unlock()
+	gs.mu.RLock()
+	save := SaveFile{
+		Version:      saveVersion,
+		SavedAt:      time.Now(),
+		ScenarioHash: gs.scenario.Hash(),
+		Player:       copyPlayer(gs.Player),
+		NextUnitID:   gs.NextUnitID,
+		Alliances:    gs.alliances.Snapshot(),
+	}
+	gs.mu.RUnlock()
+	data, err := json.MarshalIndent(save, "", "  ")
+	if err != nil {
+		return fmt.Errorf("could not encode save: %v", err)
+	}
+	f, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("could not save game: %v", err)
+	}
+	_, err = f.Write(data)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), path)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return fmt.Errorf("could not save game: %v", err)
+	}
+	return nil
+}
+
+func LoadSave(path string) (SaveFile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return SaveFile{}, fmt.Errorf("could not read save: %v", err)
+	}
+	save := SaveFile{}
+	err = json.Unmarshal(data, &save)
+	if err != nil {
+		return SaveFile{}, fmt.Errorf("save %s is corrupt: %v", path, err)
+	}
+	switch {
+	case save.Version == 0:
+		return SaveFile{}, fmt.Errorf("save %s has no version", path)
+	case save.Version > saveVersion:
+		return SaveFile{}, fmt.Errorf("save %s has version %d but this client only reads up to version %d", path, save.Version, saveVersion)
+	}
+	return save, nil
+}
+
+// Restore replaces the player's army with a saved one. The save must be
+// the same player's, in the same scenario.
+func (gs *GameState) Restore(save SaveFile) error {
+	if save.Player.Username != gs.GetUsername() {
+		return fmt.Errorf("the save belongs to %s, not %s", save.Player.Username, gs.GetUsername())
+	}
+	sc := gs.Scenario()
+	if save.ScenarioHash != sc.Hash() {
+		return fmt.Errorf("the save was made in a different scenario (%.12s) than %s (%s)", save.ScenarioHash, sc.Name, sc.ShortHash())
+	}
+	gs.mu.Lock()
+	if save.NextUnitID > gs.NextUnitID {
+		gs.NextUnitID = save.NextUnitID
+	}
+	gs.mu.Unlock()
+	gs.record(Event{
+		Kind:      EventArmyRestored,
+		Units:     sortedUnits(save.Player.Units),
+		Gold:      save.Player.Gold,
+		Alliances: &save.Alliances,
+		Reason:    "saved at " + save.SavedAt.Local().Format(time.DateTime),
+	})
+	return nil
+}
@@ -0,0 +1,68 @@
+package gamelogic
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSaveAndRestoreArmy(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "napoleon.save.json")
+	gs := NewGameState("napoleon")
+	require.NoError(t, gs.CommandSpawn([]string{"spawn", "europe", "infantry"}))
+	require.NoError(t, gs.CommandSpawn([]string{"spawn", "asia", "cavalry"}))
+	require.NoError(t, gs.Save(path))
+
+	save, err := LoadSave(path)
+	require.NoError(t, err)
+	require.Equal(t, saveVersion, save.Version)
+
+	restarted := NewGameState("napoleon")
+	require.NoError(t, restarted.Restore(save))
+	require.Equal(t, gs.GetPlayerSnap(), restarted.GetPlayerSnap())
+	require.Equal(t, 6, restarted.GetGold())
+	require.NoError(t, restarted.CommandSpawn([]string{"spawn", "europe", "infantry"}))
+	_, ok := restarted.GetUnit(3)
+	require.True(t, ok, "restored players keep counting unit IDs")
+
+	require.Error(t, NewGameState("washington").Restore(save))
+}
+
+func TestConcurrentSavesLeaveOneCompleteSave(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "napoleon.save.json")
+	gs := NewGameState("napoleon")
+	require.NoError(t, gs.CommandSpawn([]string{"spawn", "europe", "infantry"}))
+
+	wg := sync.WaitGroup{}
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			require.NoError(t, gs.Save(path))
+		}()
+	}
+	wg.Wait()
+	save, err := LoadSave(path)
+	require.NoError(t, err)
+	require.Len(t, save.Player.Units, 1)
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, entries, 1, "no temporary files are left behind")
+}
+
+func TestLoadSaveRefusesUnknownVersions(t *testing.T) {
+	dir := t.TempDir()
+	newer := filepath.Join(dir, "newer.json")
+	require.NoError(t, os.WriteFile(newer, []byte(`{"Version":99}`), 0644))
+	_, err := LoadSave(newer)
+	require.ErrorContains(t, err, "only reads up to version 2")
+
+	unversioned := filepath.Join(dir, "unversioned.json")
+	require.NoError(t, os.WriteFile(unversioned, []byte(`{}`), 0644))
+	_, err = LoadSave(unversioned)
+	require.Error(t, err)
+}
@@ -0,0 +1,363 @@
+package gamelogic
+
+import (
+	"crypto/sha256"
+	_ "embed"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+//go:embed scenarios/classic.json
+var classicScenario []byte
+
+// Scenario describes the map, the units that can be spawned and how every
+// player starts. Client and server must load the same one.
+type Scenario struct {
+	Name        string      `json:"name" yaml:"name"`
+	Territories []Territory `json:"territories" yaml:"territories"`
+	Edges       []Edge      `json:"edges" yaml:"edges"`
+	Units       []UnitType  `json:"units" yaml:"units"`
+	Start       Start       `json:"start" yaml:"start"`
+	Economy     Economy     `json:"economy" yaml:"economy"`
+	Terrains    []Terrain   `json:"terrains" yaml:"terrains"`
+	Combat      Combat      `json:"combat" yaml:"combat"`
+	Victory     Victory     `json:"victory" yaml:"victory"`
+
+	worldMap *WorldMap
+	hash     string
+}
+
+type Territory struct {
+	Name    Location `json:"name" yaml:"name"`
+	Terrain string   `json:"terrain" yaml:"terrain"`
+}
+
+type Edge struct {
+	From Location `json:"from" yaml:"from"`
+	To   Location `json:"to" yaml:"to"`
+	Cost int      `json:"cost" yaml:"cost"`
+}
+
+// UnitType describes a rank. Units start every battle with HP hit points,
+// which default to 1.
+type UnitType struct {
+	Rank  UnitRank `json:"rank" yaml:"rank"`
+	Power int      `json:"power" yaml:"power"`
+	Cost  int      `json:"cost" yaml:"cost"`
+	Speed int      `json:"speed" yaml:"speed"`
+	HP    int      `json:"hp" yaml:"hp"`
+}
+
+// Terrain gives defenders in territories of that terrain a percentage bonus
+// to the damage they deal.
+type Terrain struct {
+	Name    string `json:"name" yaml:"name"`
+	Defense int    `json:"defense" yaml:"defense"`
+}
+
+// Combat tunes the dice resolver: how many sides the dice have, how many
+// rounds a battle lasts at most and the percentage bonus every defender
+// gets on top of its terrain.
+type Combat struct {
+	Dice          int `json:"dice" yaml:"dice"`
+	Rounds        int `json:"rounds" yaml:"rounds"`
+	DefenderBonus int `json:"defender_bonus" yaml:"defender_bonus"`
+}
+
+// Start lists the units every new player is given and the gold they have
+// left to spend.
+type Start struct {
+	Gold  int         `json:"gold" yaml:"gold"`
+	Units []StartUnit `json:"units" yaml:"units"`
+}
+
+// Economy pays every player Income gold per territory they control once
+// every TickSeconds. A zero TickSeconds turns income off.
+type Economy struct {
+	Income      int `json:"income" yaml:"income"`
+	TickSeconds int `json:"tick_seconds" yaml:"tick_seconds"`
+}
+
+// Victory ends the game as soon as one player controls Territories
+// territories or, with Elimination, is the last one standing. After
+// TimeLimitSeconds of play the highest score wins. Zero values turn a
+// condition off.
+type Victory struct {
+	Territories      int  `json:"territories" yaml:"territories"`
+	Elimination      bool `json:"elimination" yaml:"elimination"`
+	TimeLimitSeconds int  `json:"time_limit_seconds" yaml:"time_limit_seconds"`
+}
+
+type StartUnit struct {
+	Rank     UnitRank `json:"rank" yaml:"rank"`
+	Location Location `json:"location" yaml:"location"`
+}
+
+// DefaultScenario is the classic six-continent map the game shipped with.
+func DefaultScenario() *Scenario {
+	sc, err := ParseScenario(classicScenario, ".json")
+	if err != nil {
+		panic("embedded scenario is invalid: " + err.Error())
+	}
+	return sc
+}
+
+// LoadScenario reads a scenario file; YAML is used for .yaml and .yml files
+// and JSON otherwise.
+func LoadScenario(path string) (*Scenario, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not read scenario: %v", err)
+	}
+	sc, err := ParseScenario(data, filepath.Ext(path))
+	if err != nil {
+		return nil, fmt.Errorf("scenario %s: %v", path, err)
+	}
+	return sc, nil
+}
+
+func ParseScenario(data []byte, ext string) (*Scenario, error) {
+	sc := &Scenario{}
+	var err error
+	switch strings.ToLower(ext) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, sc)
+	default:
+		err = json.Unmarshal(data, sc)
+	}
+	if err != nil {
+		return nil, err
+	}
+	err = sc.init()
+	if err != nil {
+		return nil, err
+	}
+	return sc, nil
+}
+
+// init validates the scenario, sorts it into canonical order and builds the
+// map and hash from it.
+func (sc *Scenario) init() error {
+	if sc.Name == "" {
+		return errors.New("scenario has no name")
+	}
+	if len(sc.Territories) == 0 {
+		return errors.New("scenario has no territories")
+	}
+	if len(sc.Units) == 0 {
+		return errors.New("scenario has no unit types")
+	}
+
+	m := NewWorldMap()
+	for _, t := range sc.Territories {
+		if t.Name == "" {
+			return errors.New("territory has no name")
+		}
+		if _, ok := m.edges[t.Name]; ok {
+			return fmt.Errorf("territory %s is listed twice", t.Name)
+		}
+		m.AddLocation(t.Name)
+	}
+	for i, e := range sc.Edges {
+		if _, ok := m.edges[e.From]; !ok {
+			return fmt.Errorf("edge %s -> %s: unknown territory %s", e.From, e.To, e.From)
+		}
+		if _, ok := m.edges[e.To]; !ok {
+			return fmt.Errorf("edge %s -> %s: unknown territory %s", e.From, e.To, e.To)
+		}
+		if e.From == e.To {
+			return fmt.Errorf("edge %s -> %s connects a territory to itself", e.From, e.To)
+		}
+		if e.Cost <= 0 {
+			return fmt.Errorf("edge %s -> %s must cost at least 1", e.From, e.To)
+		}
+		if m.IsAdjacent(e.From, e.To) {
+			return fmt.Errorf("edge %s -> %s is listed twice", e.From, e.To)
+		}
+		if e.From > e.To {
+			sc.Edges[i].From, sc.Edges[i].To = e.To, e.From
+		}
+		m.Connect(e.From, e.To, e.Cost)
+	}
+
+	ranks := map[UnitRank]struct{}{}
+	for i, u := range sc.Units {
+		if u.Rank == "" {
+			return errors.New("unit type has no rank")
+		}
+		if _, ok := ranks[u.Rank]; ok {
+			return fmt.Errorf("unit type %s is listed twice", u.Rank)
+		}
+		if u.Power < 0 || u.Cost < 0 || u.Speed < 0 || u.HP < 0 {
+			return fmt.Errorf("unit type %s has a negative power, cost, speed or hp", u.Rank)
+		}
+		if u.HP == 0 {
+			sc.Units[i].HP = 1
+		}
+		ranks[u.Rank] = struct{}{}
+	}
+
+	terrains := map[string]struct{}{}
+	for _, t := range sc.Terrains {
+		if _, ok := terrains[t.Name]; ok {
+			return fmt.Errorf("terrain %s is listed twice", t.Name)
+		}
+		terrains[t.Name] = struct{}{}
+	}
+	if sc.Victory.Territories < 0 || sc.Victory.TimeLimitSeconds < 0 {
+		return errors.New("victory territories and time limit can not be negative")
+	}
+	if sc.Combat.Dice < 0 || sc.Combat.Rounds < 0 {
+		return errors.New("combat dice and rounds can not be negative")
+	}
+	if sc.Combat.Dice == 0 {
+		sc.Combat.Dice = 6
+	}
+	if sc.Combat.Rounds == 0 {
+		sc.Combat.Rounds = 3
+	}
+	if sc.Start.Gold < 0 || sc.Economy.Income < 0 || sc.Economy.TickSeconds < 0 {
+		return errors.New("starting gold, income and tick length can not be negative")
+	}
+	for _, u := range sc.Start.Units {
+		if _, ok := ranks[u.Rank]; !ok {
+			return fmt.Errorf("starting unit has unknown rank %s", u.Rank)
+		}
+		if _, ok := m.edges[u.Location]; !ok {
+			return fmt.Errorf("starting unit is in unknown territory %s", u.Location)
+		}
+	}
+
+	sort.Slice(sc.Territories, func(i, j int) bool {
+		return sc.Territories[i].Name < sc.Territories[j].Name
+	})
+	sort.Slice(sc.Edges, func(i, j int) bool {
+		if sc.Edges[i].From == sc.Edges[j].From {
+			return sc.Edges[i].To < sc.Edges[j].To
+		}
+		return sc.Edges[i].From < sc.Edges[j].From
+	})
+	sort.Slice(sc.Units, func(i, j int) bool {
+		return sc.Units[i].Rank < sc.Units[j].Rank
+	})
+	sort.Slice(sc.Terrains, func(i, j int) bool {
+		return sc.Terrains[i].Name < sc.Terrains[j].Name
+	})
+	if sc.Start.Units == nil {
+		sc.Start.Units = []StartUnit{}
+	}
+	if sc.Edges == nil {
+		sc.Edges = []Edge{}
+	}
+	if sc.Terrains == nil {
+		sc.Terrains = []Terrain{}
+	}
+
+	canonical, err := json.Marshal(sc)
+	if err != nil {
+		return err
+	}
+	sum := sha256.Sum256(canonical)
+	sc.hash = hex.EncodeToString(sum[:])
+	sc.worldMap = m
+	return nil
+}
+
+// Hash identifies the scenario's content, independent of how the file was
+// formatted or ordered.
+func (sc *Scenario) Hash() string {
+	return sc.hash
+}
+
+// ShortHash is enough of the hash to tell scenarios apart in messages.
+func (sc *Scenario) ShortHash() string {
+	return sc.hash[:12]
+}
+
+func (sc *Scenario) Map() *WorldMap {
+	return sc.worldMap
+}
+
+func (sc *Scenario) UnitType(rank UnitRank) (UnitType, bool) {
+	for _, u := range sc.Units {
+		if u.Rank == rank {
+			return u, true
+		}
+	}
+	return UnitType{}, false
+}
+
+func (sc *Scenario) Terrain(loc Location) string {
+	for _, t := range sc.Territories {
+		if t.Name == loc {
+			return t.Terrain
+		}
+	}
+	return ""
+}
+
+// DefenseBonus is the percentage added to a defender's damage in loc.
+func (sc *Scenario) DefenseBonus(loc Location) int {
+	bonus := sc.Combat.DefenderBonus
+	terrain := sc.Terrain(loc)
+	for _, t := range sc.Terrains {
+		if t.Name == terrain {
+			bonus += t.Defense
+		}
+	}
+	return bonus
+}
+
+// TickInterval is how often the server pays income, or zero if never.
+func (sc *Scenario) TickInterval() time.Duration {
+	return time.Duration(sc.Economy.TickSeconds) * time.Second
+}
+
+func (sc *Scenario) UnitCost(rank UnitRank) int {
+	u, _ := sc.UnitType(rank)
+	return u.Cost
+}
+
+// startingBalance covers the starting units on top of the starting gold,
+// since they are paid for like any other spawn.
+func (sc *Scenario) startingBalance() int {
+	gold := sc.Start.Gold
+	for _, u := range sc.Start.Units {
+		gold += sc.UnitCost(u.Rank)
+	}
+	return gold
+}
+
+func (sc *Scenario) PowerLevel(units []Unit) int {
+	power := 0
+	for _, unit := range units {
+		u, _ := sc.UnitType(unit.Rank)
+		power += u.Power
+	}
+	return power
+}
+
+// checkMove reports why a unit can not reach loc in one move, if it can't.
+func (sc *Scenario) checkMove(unit Unit, loc Location) error {
+	if unit.Location == loc {
+		return nil
+	}
+	path, cost, err := sc.worldMap.ShortestPath(unit.Location, loc)
+	if err != nil {
+		return err
+	}
+	u, _ := sc.UnitType(unit.Rank)
+	if cost > u.Speed {
+		return fmt.Errorf("error: %s %v can move %d but %s costs %d", unit.Rank, unit.ID, u.Speed, formatPath(path), cost)
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+package gamelogic
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDefaultScenarioMatchesClassicRules(t *testing.T) {
+	sc := DefaultScenario()
+	require.Len(t, sc.Map().Locations(), 6)
+	require.Equal(t, 16, sc.PowerLevel([]Unit{
+		{Rank: RankInfantry},
+		{Rank: RankCavalry},
+		{Rank: RankArtillery},
+	}))
+	cavalry, ok := sc.UnitType(RankCavalry)
+	require.True(t, ok)
+	require.Equal(t, 4, cavalry.Speed)
+}
+
+func TestScenarioHashIgnoresFormatting(t *testing.T) {
+	a, err := ParseScenario([]byte(`{
+		"name": "tiny",
+		"territories": [{"name": "a"}, {"name": "b"}],
+		"edges": [{"from": "a", "to": "b", "cost": 1}],
+		"units": [{"rank": "infantry", "power": 1, "speed": 1}]
+	}`), ".json")
+	require.NoError(t, err)
+	b, err := ParseScenario([]byte(`
+name: tiny
+territories:
+  - name: b
+  - name: a
+edges:
+  - {from: b, to: a, cost: 1}
+units:
+  - {rank: infantry, power: 1, speed: 1}
+`), ".yaml")
+	require.NoError(t, err)
+	require.Equal(t, a.Hash(), b.Hash())
+
+	c, err := ParseScenario([]byte(`{
+		"name": "tiny",
+		"territories": [{"name": "a"}, {"name": "b"}],
+		"edges": [{"from": "a", "to": "b", "cost": 2}],
+		"units": [{"rank": "infantry", "power": 1, "speed": 1}]
+	}`), ".json")
+	require.NoError(t, err)
+	require.NotEqual(t, a.Hash(), c.Hash())
+}
+
+func TestScenarioValidation(t *testing.T) {
+	_, err := ParseScenario([]byte(`{
+		"name": "broken",
+		"territories": [{"name": "a"}],
+		"edges": [{"from": "a", "to": "nowhere", "cost": 1}],
+		"units": [{"rank": "infantry", "power": 1, "speed": 1}]
+	}`), ".json")
+	require.EqualError(t, err, "edge a -> nowhere: unknown territory nowhere")
+
+	_, err = ParseScenario([]byte(`{
+		"name": "broken",
+		"territories": [{"name": "a"}],
+		"units": [{"rank": "infantry", "power": 1, "speed": 1}],
+		"start": {"units": [{"rank": "dragon", "location": "a"}]}
+	}`), ".json")
+	require.EqualError(t, err, "starting unit has unknown rank dragon")
+}
+
+func TestStartingUnitsOnlyForNewPlayers(t *testing.T) {
+	sc, err := ParseScenario([]byte(`{
+		"name": "armed",
+		"territories": [{"name": "a"}],
+		"units": [{"rank": "infantry", "power": 1, "speed": 1}],
+		"start": {"units": [{"rank": "infantry", "location": "a"}, {"rank": "infantry", "location": "a"}]}
+	}`), ".json")
+	require.NoError(t, err)
+	gs := NewGameState("napoleon")
+	gs.UseScenario(sc)
+
+	units, err := gs.PlanStartingUnits()
+	require.NoError(t, err)
+	require.Len(t, units, 2)
+	require.Equal(t, "napoleon", units[0].Owner)
+	require.Equal(t, 2, units[1].ID)
+
+	units, err = gs.PlanStartingUnits()
+	require.NoError(t, err)
+	require.Empty(t, units)
+}
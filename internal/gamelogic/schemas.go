@@ -0,0 +1,93 @@
+package gamelogic
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/bootdotdev/learn-pub-sub-starter/internal/schema"
+)
+
+// Bump a version whenever a field is removed or changes kind, and register
+// an upcaster from the previous version below.
+const (
+	armyMoveVersion         = 2
+	recognitionOfWarVersion = 2
+	playerSyncVersion       = 1
+	stateDeltaVersion       = 1
+	warResolutionVersion    = 1
+	warConfirmationVersion  = 1
+	economyTickVersion      = 1
+	gameOverVersion         = 1
+)
+
+func init() {
+	schema.Register(ArmyMove{})
+	schema.Register(RecognitionOfWar{})
+	schema.Register(PlayerSync{})
+	schema.Register(StateDelta{})
+	schema.Register(WarResolution{})
+	schema.Register(WarConfirmation{})
+	schema.Register(EconomyTick{})
+	schema.Register(GameOver{})
+
+	schema.RegisterUpcaster(ArmyMove{}.SchemaName(), 1, upcastJSON(func(m *ArmyMove) {
+		m.Player = withOwner(m.Player)
+		for i := range m.Units {
+			m.Units[i].Owner = m.Player.Username
+		}
+	}))
+	schema.RegisterUpcaster(RecognitionOfWar{}.SchemaName(), 1, upcastJSON(func(rw *RecognitionOfWar) {
+		rw.Attacker = withOwner(rw.Attacker)
+		rw.Defender = withOwner(rw.Defender)
+	}))
+}
+
+// upcastJSON builds an upcaster for changes that only need fields filled in
+// on the current struct.
+func upcastJSON[T any](fill func(*T)) schema.Upcaster {
+	return func(contentType string, body []byte) ([]byte, error) {
+		if contentType != "application/json" {
+			return nil, fmt.Errorf("can not upcast %s", contentType)
+		}
+		var val T
+		err := json.Unmarshal(body, &val)
+		if err != nil {
+			return nil, err
+		}
+		fill(&val)
+		return json.Marshal(val)
+	}
+}
+
+// Before v2, units did not carry the name of the player owning them.
+func withOwner(p Player) Player {
+	for id, unit := range p.Units {
+		unit.Owner = p.Username
+		p.Units[id] = unit
+	}
+	return p
+}
+
+func (ArmyMove) SchemaName() string { return "army_move" }
+func (ArmyMove) SchemaVersion() int { return armyMoveVersion }
+
+func (RecognitionOfWar) SchemaName() string { return "recognition_of_war" }
+func (RecognitionOfWar) SchemaVersion() int { return recognitionOfWarVersion }
+
+func (PlayerSync) SchemaName() string { return "player_sync" }
+func (PlayerSync) SchemaVersion() int { return playerSyncVersion }
+
+func (StateDelta) SchemaName() string { return "state_delta" }
+func (StateDelta) SchemaVersion() int { return stateDeltaVersion }
+
+func (WarResolution) SchemaName() string { return "war_resolution" }
+func (WarResolution) SchemaVersion() int { return warResolutionVersion }
+
+func (WarConfirmation) SchemaName() string { return "war_confirmation" }
+func (WarConfirmation) SchemaVersion() int { return warConfirmationVersion }
+
+func (EconomyTick) SchemaName() string { return "economy_tick" }
+func (EconomyTick) SchemaVersion() int { return economyTickVersion }
+
+func (GameOver) SchemaName() string { return "game_over" }
+func (GameOver) SchemaVersion() int { return gameOverVersion }
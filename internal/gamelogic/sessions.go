@@ -0,0 +1,124 @@
+package gamelogic
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/bootdotdev/learn-pub-sub-starter/internal/routing"
+)
+
+// Sessions reserves usernames in a game. The first player to join under a
+// name gets a token, and only that token can join under the name again.
+type Sessions struct {
+	tokens map[string]string
+	path   string
+	mu     *sync.Mutex
+}
+
+func NewSessions() *Sessions {
+	return &Sessions{
+		tokens: map[string]string{},
+		mu:     &sync.Mutex{},
+	}
+}
+
+// LoadSessions keeps the sessions in the file at path, so that players keep
+// their names when the server restarts.
+func LoadSessions(path string) (*Sessions, error) {
+	s := NewSessions()
+	s.path = path
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not read sessions: %v", err)
+	}
+	err = json.Unmarshal(data, &s.tokens)
+	if err != nil {
+		return nil, fmt.Errorf("sessions file %s is corrupt: %v", path, err)
+	}
+	return s, nil
+}
+
+// Claim reserves username, or reclaims it with token, and returns the
+// session token. Names nobody holds get a new token, whatever token the
+// player presents.
+func (s *Sessions) Claim(username, token string) (string, error) {
+	err := routing.ValidateUsername(username)
+	if err != nil {
+		return "", err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	known, ok := s.tokens[username]
+	if ok {
+		if token != known {
+			return "", fmt.Errorf("the username %s is already taken in this game; pick another one, or rejoin from the machine that first used it", username)
+		}
+		return known, nil
+	}
+	token, err = newToken()
+	if err != nil {
+		return "", err
+	}
+	s.tokens[username] = token
+	err = s.save()
+	if err != nil {
+		delete(s.tokens, username)
+		return "", err
+	}
+	return token, nil
+}
+
+// Verify reports whether token is the session token of username.
+func (s *Sessions) Verify(username, token string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	known, ok := s.tokens[username]
+	return ok && token != "" && token == known
+}
+
+// save writes the sessions to their file, if they have one. The caller
+// holds s.mu.
+func (s *Sessions) save() error {
+	if s.path == "" {
+		return nil
+	}
+	data, err := json.Marshal(s.tokens)
+	if err != nil {
+		return fmt.Errorf("could not encode sessions: %v", err)
+	}
+	f, err := os.CreateTemp(filepath.Dir(s.path), "."+filepath.Base(s.path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("could not save sessions: %v", err)
+	}
+	_, err = f.Write(data)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), s.path)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return fmt.Errorf("could not save sessions: %v", err)
+	}
+	return nil
+}
+
+// Tokens are secrets, so they come from crypto/rand rather than the game
+// seed.
+func newToken() (string, error) {
+	b := make([]byte, 16)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", fmt.Errorf("could not create a session token: %v", err)
+	}
+	return hex.EncodeToString(b), nil
+}
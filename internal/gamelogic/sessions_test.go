@@ -0,0 +1,67 @@
+package gamelogic
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSessionsReserveUsernames(t *testing.T) {
+	s := NewSessions()
+	token, err := s.Claim("napoleon", "")
+	require.NoError(t, err)
+	require.Len(t, token, 32)
+
+	_, err = s.Claim("napoleon", "")
+	require.ErrorContains(t, err, "the username napoleon is already taken")
+	_, err = s.Claim("napoleon", "not-the-token")
+	require.Error(t, err)
+
+	again, err := s.Claim("napoleon", token)
+	require.NoError(t, err)
+	require.Equal(t, token, again)
+}
+
+func TestSessionsIgnoreTokensForUnknownNames(t *testing.T) {
+	s := NewSessions()
+	token, err := s.Claim("washington", "0123456789abcdef")
+	require.NoError(t, err)
+	require.NotEqual(t, "0123456789abcdef", token)
+	_, err = s.Claim("washington", "0123456789abcdef")
+	require.Error(t, err)
+}
+
+func TestSessionsVerifyTokens(t *testing.T) {
+	s := NewSessions()
+	token, err := s.Claim("napoleon", "")
+	require.NoError(t, err)
+	require.True(t, s.Verify("napoleon", token))
+	require.False(t, s.Verify("napoleon", "not-the-token"))
+	require.False(t, s.Verify("washington", token))
+	require.False(t, s.Verify("washington", ""))
+}
+
+func TestSessionsSurviveRestarts(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "default.json")
+	s, err := LoadSessions(path)
+	require.NoError(t, err)
+	token, err := s.Claim("washington", "")
+	require.NoError(t, err)
+
+	restarted, err := LoadSessions(path)
+	require.NoError(t, err)
+	_, err = restarted.Claim("washington", "")
+	require.Error(t, err, "the name is still taken after a restart")
+	again, err := restarted.Claim("washington", token)
+	require.NoError(t, err)
+	require.Equal(t, token, again)
+}
+
+func TestSessionsRefuseUnsafeUsernames(t *testing.T) {
+	s := NewSessions()
+	for _, username := range []string{"", "napoleon.bonaparte", "*", "../napoleon"} {
+		_, err := s.Claim(username, "")
+		require.Error(t, err, username)
+	}
+}
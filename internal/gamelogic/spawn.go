@@ -6,29 +6,96 @@ import (
 )
 
 func (gs *GameState) CommandSpawn(words []string) error {
+	unit, err := gs.PlanSpawn(words)
+	if err != nil {
+		return err
+	}
+	gs.ApplySpawn(unit)
+	return nil
+}
+
+// PlanSpawn validates a spawn command and builds the new unit without adding
+// it, so the caller can record the spawn before applying it.
+func (gs *GameState) PlanSpawn(words []string) (Unit, error) {
 	if len(words) < 3 {
-		return errors.New("usage: spawn <location> <rank>")
+		return Unit{}, errors.New("usage: spawn <location> <rank>")
+	}
+	_, err := gs.checkOrderWindow()
+	if err != nil {
+		return Unit{}, err
 	}
 
 	locationName := words[1]
-	locations := getAllLocations()
+	sc := gs.Scenario()
+	locations := sc.Map().Locations()
 	if _, ok := locations[Location(locationName)]; !ok {
-		return fmt.Errorf("error: %s is not a valid location", locationName)
+		return Unit{}, fmt.Errorf("error: %s is not a valid location", locationName)
 	}
 
 	rank := words[2]
-	units := getAllRanks()
-	if _, ok := units[UnitRank(rank)]; !ok {
-		return fmt.Errorf("error: %s is not a valid unit", rank)
+	unitType, ok := sc.UnitType(UnitRank(rank))
+	if !ok {
+		return Unit{}, fmt.Errorf("error: %s is not a valid unit", rank)
+	}
+	if gold := gs.GetGold(); unitType.Cost > gold {
+		return Unit{}, fmt.Errorf("error: a(n) %s costs %d gold but you only have %d", rank, unitType.Cost, gold)
 	}
 
-	id := len(gs.getUnitsSnap()) + 1
-	gs.addUnit(Unit{
+	id, err := gs.allocateUnitID()
+	if err != nil {
+		return Unit{}, err
+	}
+	return Unit{
 		ID:       id,
+		Owner:    gs.GetUsername(),
 		Rank:     UnitRank(rank),
 		Location: Location(locationName),
+	}, nil
+}
+
+// ApplySpawn adds the unit and pays for it. The server keeps its own
+// balance and corrects ours on the next economic tick.
+func (gs *GameState) ApplySpawn(unit Unit) {
+	gs.record(Event{
+		Kind:  EventUnitSpawned,
+		Units: []Unit{unit},
+		Cost:  gs.Scenario().UnitCost(unit.Rank),
 	})
+	gold := gs.GetGold()
+	fmt.Printf("Spawned a(n) %s in %s with id %v (%d gold left)\n", unit.Rank, unit.Location, unit.ID, gold)
+}
 
-	fmt.Printf("Spawned a(n) %s in %s with id %v\n", rank, locationName, id)
-	return nil
+// PlanStartingUnits builds the scenario's starting army for a player who has
+// never spawned a unit before, and nothing for anyone else.
+func (gs *GameState) PlanStartingUnits() ([]Unit, error) {
+	gs.mu.RLock()
+	fresh := gs.NextUnitID == 1 && len(gs.Player.Units) == 0
+	start := gs.scenario.Start.Units
+	gs.mu.RUnlock()
+	if !fresh {
+		return nil, nil
+	}
+	units := []Unit{}
+	for _, su := range start {
+		id, err := gs.allocateUnitID()
+		if err != nil {
+			return nil, err
+		}
+		units = append(units, Unit{
+			ID:       id,
+			Owner:    gs.GetUsername(),
+			Rank:     su.Rank,
+			Location: su.Location,
+		})
+	}
+	return units, nil
+}
+
+// SpawnSync is the snapshot the server needs after units are spawned.
+func (gs *GameState) SpawnSync(units ...Unit) PlayerSync {
+	player := gs.GetPlayerSnap()
+	for _, unit := range units {
+		player.Units[unit.ID] = unit
+	}
+	return PlayerSync{Player: player}
 }
@@ -0,0 +1,20 @@
+package gamelogic
+
+import "fmt"
+
+func (gs *GameState) HandleStateDelta(d StateDelta) {
+	defer fmt.Println("------------------------")
+	fmt.Println()
+	fmt.Println("==== World Update ====")
+	fmt.Printf("The server corrected your army (%s).\n", d.Reason)
+	if len(d.Units) > 0 {
+		gs.record(Event{Kind: EventUnitMoved, Units: d.Units, Reason: d.Reason})
+	}
+	for _, unit := range d.Units {
+		fmt.Printf("* %v is in %v\n", unit.ID, unit.Location)
+	}
+	if len(d.Removed) > 0 {
+		gs.record(Event{Kind: EventUnitsDestroyed, IDs: d.Removed, Reason: d.Reason})
+		fmt.Printf("* units %v no longer exist\n", d.Removed)
+	}
+}
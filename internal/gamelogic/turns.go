@@ -0,0 +1,240 @@
+package gamelogic
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/bootdotdev/learn-pub-sub-starter/internal/routing"
+)
+
+// UseTurns switches the player to a turn-based game, currently in ts.
+func (gs *GameState) UseTurns(ts routing.TurnState) {
+	gs.mu.Lock()
+	defer gs.mu.Unlock()
+	gs.turnBased = true
+	gs.turn = ts
+}
+
+func (gs *GameState) TurnBased() bool {
+	gs.mu.RLock()
+	defer gs.mu.RUnlock()
+	return gs.turnBased
+}
+
+func (gs *GameState) HandleTurn(ts routing.TurnState) {
+	defer fmt.Println("------------------------")
+	gs.UseTurns(ts)
+	fmt.Println()
+	if ts.Phase == routing.TurnPhaseOrders {
+		fmt.Printf("==== Turn %d ====\n", ts.Turn)
+		fmt.Printf("Give your orders before %s.\n", ts.Deadline.Local().Format(time.TimeOnly))
+		return
+	}
+	fmt.Printf("==== Turn %d is over ====\n", ts.Turn)
+	fmt.Println("The server is resolving everyone's orders.")
+}
+
+// checkOrderWindow returns the turn orders are for, or an error if the
+// player can not give orders right now. Running real-time games are always
+// open.
+func (gs *GameState) checkOrderWindow() (int, error) {
+	err := gs.checkPhase()
+	if err != nil {
+		return 0, err
+	}
+	gs.mu.RLock()
+	defer gs.mu.RUnlock()
+	if !gs.turnBased {
+		return 0, nil
+	}
+	if gs.turn.Turn == 0 {
+		return 0, fmt.Errorf("error: the first turn has not started yet")
+	}
+	if gs.turn.Phase != routing.TurnPhaseOrders || time.Now().After(gs.turn.Deadline) {
+		return 0, fmt.Errorf("error: orders for turn %d are closed, wait for the next turn", gs.turn.Turn)
+	}
+	return gs.turn.Turn, nil
+}
+
+func (gs *GameState) printTurn() {
+	gs.mu.RLock()
+	defer gs.mu.RUnlock()
+	if !gs.turnBased {
+		return
+	}
+	if gs.turn.Phase == routing.TurnPhaseOrders {
+		fmt.Printf("It is turn %d; orders close at %s.\n", gs.turn.Turn, gs.turn.Deadline.Local().Format(time.TimeOnly))
+		return
+	}
+	fmt.Printf("Turn %d is being resolved.\n", gs.turn.Turn)
+}
+
+// TurnResult is what the server has to tell players after a turn: the
+// moves applied, the corrections for rejected moves and the wars fought in
+// contested territories. Expired lists the wars of earlier turns that were
+// never confirmed by both sides.
+type TurnResult struct {
+	Turn     int
+	Moves    []ArmyMove
+	Deltas   []StateDelta
+	Wars     []WarResolution
+	Expired  []WarResolution
+	Rejected []error
+}
+
+// EnableTurns makes the world queue moves until the end of each turn.
+func (w *World) EnableTurns() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.turnBased = true
+}
+
+func (w *World) TurnBased() bool {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return w.turnBased
+}
+
+func (w *World) Turn() routing.TurnState {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return w.turn
+}
+
+// StartTurn opens orders for the next turn until deadline.
+func (w *World) StartTurn(deadline time.Time) routing.TurnState {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.turn = routing.TurnState{
+		Turn:     w.turn.Turn + 1,
+		Phase:    routing.TurnPhaseOrders,
+		Deadline: deadline,
+	}
+	return w.turn
+}
+
+// ExtendTurn moves the deadline of the open turn back by d, for example to
+// give back the time the game spent paused.
+func (w *World) ExtendTurn(d time.Duration) routing.TurnState {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.turn.Phase == routing.TurnPhaseOrders {
+		w.turn.Deadline = w.turn.Deadline.Add(d)
+	}
+	return w.turn
+}
+
+func (w *World) queueMove(move ArmyMove) (StateDelta, error) {
+	if w.turn.Phase != routing.TurnPhaseOrders || move.Turn != w.turn.Turn {
+		return w.correction(move.Player.Username, move.Units, "orders arrived outside their turn"), fmt.Errorf("move for turn %d arrived during turn %d (%s)", move.Turn, w.turn.Turn, w.turn.Phase)
+	}
+	delta, err := w.checkMove(move)
+	if err != nil {
+		return delta, err
+	}
+	w.queued = append(w.queued, move)
+	return StateDelta{}, nil
+}
+
+// EndTurn closes orders, applies every queued move in the order it arrived
+// and then fights a war in every territory held by more than one player.
+func (w *World) EndTurn(resolver CombatResolver) TurnResult {
+	w.mu.Lock()
+	w.turn.Phase = routing.TurnPhaseResolving
+	result := TurnResult{Turn: w.turn.Turn}
+	result.Expired = w.dropWars(func(war *pendingWar) bool {
+		return war.turn < w.turn.Turn
+	})
+	queued := w.queued
+	w.queued = nil
+	movers := map[Location][]string{}
+	for _, move := range queued {
+		delta, err := w.applyMove(move)
+		if err != nil {
+			result.Rejected = append(result.Rejected, fmt.Errorf("%s: %v", move.Player.Username, err))
+		} else {
+			movers[move.ToLocation] = append(movers[move.ToLocation], move.Player.Username)
+			move.Player = copyPlayer(w.players[move.Player.Username])
+			result.Moves = append(result.Moves, move)
+		}
+		if !delta.IsEmpty() {
+			result.Deltas = append(result.Deltas, delta)
+		}
+	}
+	contested := w.contested(movers)
+	w.mu.Unlock()
+
+	for _, c := range contested {
+		for i := 1; i < len(c.players); i++ {
+			attacker, ok := w.GetPlayer(c.players[i])
+			if !ok || len(unitsInLocation(attacker, c.location)) == 0 {
+				continue
+			}
+			for _, name := range c.players[:i] {
+				defender, ok := w.GetPlayer(name)
+				if !ok || len(unitsInLocation(defender, c.location)) == 0 || w.alliances.Allied(attacker.Username, name) {
+					continue
+				}
+				rw := RecognitionOfWar{Attacker: attacker, Defender: defender}
+				res := resolveWarAt(resolver, w.NextWarID(), rw, c.location)
+				w.RecordWar(res)
+				result.Wars = append(result.Wars, res)
+				break
+			}
+		}
+	}
+	return result
+}
+
+type contestedLocation struct {
+	location Location
+	players  []string
+}
+
+// contested lists the territories held by more than one player, in name
+// order. Players who were already there come first, by name, followed by
+// those who moved in this turn in the order their moves arrived; each of
+// them attacks the first player before them who still holds the territory
+// and is not their ally.
+func (w *World) contested(movers map[Location][]string) []contestedLocation {
+	present := map[Location]map[string]struct{}{}
+	for username, p := range w.players {
+		for _, unit := range p.Units {
+			if present[unit.Location] == nil {
+				present[unit.Location] = map[string]struct{}{}
+			}
+			present[unit.Location][username] = struct{}{}
+		}
+	}
+
+	result := []contestedLocation{}
+	for loc, players := range present {
+		if len(players) < 2 {
+			continue
+		}
+		moved := map[string]bool{}
+		arrivals := []string{}
+		for _, username := range movers[loc] {
+			if _, ok := players[username]; ok && !moved[username] {
+				moved[username] = true
+				arrivals = append(arrivals, username)
+			}
+		}
+		holders := []string{}
+		for username := range players {
+			if !moved[username] {
+				holders = append(holders, username)
+			}
+		}
+		sort.Strings(holders)
+		result = append(result, contestedLocation{
+			location: loc,
+			players:  append(holders, arrivals...),
+		})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].location < result[j].location
+	})
+	return result
+}
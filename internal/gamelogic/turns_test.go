@@ -0,0 +1,176 @@
+package gamelogic
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bootdotdev/learn-pub-sub-starter/internal/routing"
+	"github.com/stretchr/testify/require"
+)
+
+func syncUnits(t *testing.T, world *World, username string, units ...Unit) {
+	t.Helper()
+	player := Player{Username: username, Units: map[int]Unit{}}
+	for _, u := range units {
+		u.Owner = username
+		player.Units[u.ID] = u
+	}
+	_, err := world.HandleSync(PlayerSync{Player: player})
+	require.NoError(t, err)
+}
+
+func moveTo(username string, turn int, unit Unit, loc Location) ArmyMove {
+	unit.Owner = username
+	unit.Location = loc
+	return ArmyMove{
+		Player:     Player{Username: username, Units: map[int]Unit{unit.ID: unit}},
+		Units:      []Unit{unit},
+		ToLocation: loc,
+		Turn:       turn,
+	}
+}
+
+func TestTurnMovesWaitForTheEndOfTheTurn(t *testing.T) {
+	world := NewWorld(DefaultScenario())
+	world.EnableTurns()
+	syncUnits(t, world, "napoleon", Unit{ID: 1, Rank: RankArtillery, Location: "europe"})
+	syncUnits(t, world, "washington", Unit{ID: 1, Rank: RankInfantry, Location: "africa"})
+	ts := world.StartTurn(time.Now().Add(time.Minute))
+
+	_, err := world.HandleMove(moveTo("washington", ts.Turn, Unit{ID: 1, Rank: RankInfantry}, "europe"))
+	require.NoError(t, err)
+	washington, _ := world.GetPlayer("washington")
+	require.Equal(t, Location("africa"), washington.Units[1].Location)
+
+	result := world.EndTurn(NewPowerResolver(DefaultScenario()))
+	require.Empty(t, result.Rejected)
+	require.Len(t, result.Moves, 1)
+	require.Equal(t, Location("europe"), result.Moves[0].Player.Units[1].Location)
+	require.Len(t, result.Wars, 1)
+	require.Equal(t, "washington", result.Wars[0].Attacker)
+	require.Equal(t, "napoleon", result.Wars[0].Defender)
+	require.Equal(t, "napoleon", result.Wars[0].Winner)
+	require.Equal(t, routing.TurnPhaseResolving, world.Turn().Phase)
+}
+
+func TestTurnRejectsLateOrders(t *testing.T) {
+	world := NewWorld(DefaultScenario())
+	world.EnableTurns()
+	syncUnits(t, world, "washington", Unit{ID: 1, Rank: RankInfantry, Location: "africa"})
+	ts := world.StartTurn(time.Now().Add(time.Minute))
+	world.EndTurn(NewPowerResolver(DefaultScenario()))
+
+	delta, err := world.HandleMove(moveTo("washington", ts.Turn, Unit{ID: 1, Rank: RankInfantry}, "europe"))
+	require.Error(t, err)
+	require.Equal(t, Location("africa"), delta.Units[0].Location)
+}
+
+func TestOrdersOnlyInsideTheWindow(t *testing.T) {
+	gs := NewGameState("napoleon")
+	gs.UseTurns(routing.TurnState{Turn: 3, Phase: routing.TurnPhaseResolving})
+	_, err := gs.PlanSpawn([]string{"spawn", "europe", "infantry"})
+	require.EqualError(t, err, "error: orders for turn 3 are closed, wait for the next turn")
+
+	gs.UseTurns(routing.TurnState{Turn: 4, Phase: routing.TurnPhaseOrders, Deadline: time.Now().Add(time.Minute)})
+	require.NoError(t, gs.CommandSpawn([]string{"spawn", "europe", "infantry"}))
+	mv, err := gs.PlanMove([]string{"move", "asia", "1"})
+	require.NoError(t, err)
+	require.Equal(t, 4, mv.Turn)
+}
+
+func TestTurnKeepsUnitsSpawnedAfterAnOrder(t *testing.T) {
+	world := NewWorld(DefaultScenario())
+	world.EnableTurns()
+	syncUnits(t, world, "napoleon", Unit{ID: 1, Rank: RankInfantry, Location: "europe"})
+	ts := world.StartTurn(time.Now().Add(time.Minute))
+	_, err := world.HandleMove(moveTo("napoleon", ts.Turn, Unit{ID: 1, Rank: RankInfantry}, "asia"))
+	require.NoError(t, err)
+	syncUnits(t, world, "napoleon",
+		Unit{ID: 1, Rank: RankInfantry, Location: "asia"},
+		Unit{ID: 2, Rank: RankCavalry, Location: "europe"},
+	)
+	before, _ := world.GetPlayer("napoleon")
+
+	result := world.EndTurn(NewPowerResolver(DefaultScenario()))
+	require.Empty(t, result.Rejected)
+	require.Empty(t, result.Deltas)
+	after, _ := world.GetPlayer("napoleon")
+	require.Len(t, after.Units, 2)
+	require.Equal(t, Location("asia"), after.Units[1].Location)
+	require.Equal(t, Location("europe"), after.Units[2].Location)
+	require.Equal(t, before.Gold, after.Gold)
+}
+
+func TestTurnRefusesUnitsSpawnedOutsideTheOrders(t *testing.T) {
+	world := NewWorld(DefaultScenario())
+	world.EnableTurns()
+	syncUnits(t, world, "napoleon", Unit{ID: 1, Rank: RankInfantry, Location: "europe"})
+	before, _ := world.GetPlayer("napoleon")
+
+	delta, err := world.HandleSync(PlayerSync{Player: Player{Username: "napoleon", Units: map[int]Unit{
+		1: {ID: 1, Rank: RankInfantry, Location: "europe", Owner: "napoleon"},
+		2: {ID: 2, Rank: RankCavalry, Location: "europe", Owner: "napoleon"},
+	}}})
+	require.NoError(t, err)
+	require.Equal(t, []int{2}, delta.Removed)
+	after, _ := world.GetPlayer("napoleon")
+	require.Len(t, after.Units, 1)
+	require.Equal(t, before.Gold, after.Gold)
+
+	world.StartTurn(time.Now().Add(time.Minute))
+	syncUnits(t, world, "napoleon",
+		Unit{ID: 1, Rank: RankInfantry, Location: "europe"},
+		Unit{ID: 2, Rank: RankCavalry, Location: "europe"},
+	)
+	after, _ = world.GetPlayer("napoleon")
+	require.Len(t, after.Units, 2, "spawns are fine while orders are open")
+
+	world.EndTurn(NewPowerResolver(DefaultScenario()))
+	delta, err = world.HandleSync(PlayerSync{Player: Player{Username: "napoleon", Units: map[int]Unit{
+		1: {ID: 1, Rank: RankInfantry, Location: "europe", Owner: "napoleon"},
+		2: {ID: 2, Rank: RankCavalry, Location: "europe", Owner: "napoleon"},
+		3: {ID: 3, Rank: RankArtillery, Location: "europe", Owner: "napoleon"},
+	}}})
+	require.NoError(t, err)
+	require.Equal(t, []int{3}, delta.Removed)
+}
+
+func TestExtendTurnMovesTheDeadlineOfOpenOrders(t *testing.T) {
+	world := NewWorld(DefaultScenario())
+	world.EnableTurns()
+	deadline := time.Now().Add(time.Minute)
+	world.StartTurn(deadline)
+	require.Equal(t, deadline.Add(30*time.Second), world.ExtendTurn(30*time.Second).Deadline)
+
+	world.EndTurn(NewPowerResolver(DefaultScenario()))
+	require.Equal(t, deadline.Add(30*time.Second), world.ExtendTurn(time.Minute).Deadline, "closed orders stay closed")
+}
+
+func TestUnconfirmedWarsExpire(t *testing.T) {
+	world := NewWorld(DefaultScenario())
+	world.EnableTurns()
+	syncUnits(t, world, "napoleon", Unit{ID: 1, Rank: RankArtillery, Location: "europe"})
+	syncUnits(t, world, "washington", Unit{ID: 1, Rank: RankInfantry, Location: "africa"})
+	ts := world.StartTurn(time.Now().Add(time.Minute))
+	_, err := world.HandleMove(moveTo("washington", ts.Turn, Unit{ID: 1, Rank: RankInfantry}, "europe"))
+	require.NoError(t, err)
+	result := world.EndTurn(NewPowerResolver(DefaultScenario()))
+	require.Len(t, result.Wars, 1)
+	war := result.Wars[0]
+
+	_, done, err := world.ConfirmWar(WarConfirmation{WarID: war.ID, Username: "napoleon"})
+	require.NoError(t, err)
+	require.False(t, done)
+
+	world.StartTurn(time.Now().Add(time.Minute))
+	result = world.EndTurn(NewPowerResolver(DefaultScenario()))
+	require.Len(t, result.Expired, 1)
+	require.Equal(t, war.ID, result.Expired[0].ID)
+	_, _, err = world.ConfirmWar(WarConfirmation{WarID: war.ID, Username: "washington"})
+	require.Error(t, err, "the war stopped waiting for washington")
+
+	world.RecordWar(war)
+	require.Empty(t, world.AbandonWars("wellington"))
+	require.Len(t, world.AbandonWars("washington"), 1)
+	require.Empty(t, world.AbandonWars("napoleon"))
+}
@@ -0,0 +1,117 @@
+package gamelogic
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// Undo takes back the player's latest spawn or move. The server keeps the
+// gold a spawn cost, so an undo never gives it back.
+type Undo struct {
+	Seq  int
+	Kind string
+	// Removed lists the units a spawn brought, which go away again.
+	Removed []int
+	// Units are the units a move took, back where they were before it, and
+	// Moves the orders that bring them back on the server.
+	Units []Unit
+	Moves []ArmyMove
+}
+
+// PlanUndo works out how to take back the latest spawn or move by
+// rebuilding the army from the events before it. Undoing again takes back
+// the one before, as long as nothing else happened in between.
+func (gs *GameState) PlanUndo() (Undo, error) {
+	if gs.isPaused() {
+		return Undo{}, errors.New("the game is paused, you can not undo")
+	}
+	turn, err := gs.checkOrderWindow()
+	if err != nil {
+		return Undo{}, err
+	}
+	username := gs.GetUsername()
+	kept := undoable(gs.Events())
+	if len(kept) == 0 {
+		return Undo{}, errors.New("error: there is nothing to undo")
+	}
+	last := kept[len(kept)-1]
+	u := Undo{Seq: last.Seq, Kind: last.Kind}
+	switch last.Kind {
+	case EventUnitSpawned:
+		for _, unit := range last.Units {
+			u.Removed = append(u.Removed, unit.ID)
+		}
+		return u, nil
+	case EventUnitMoved:
+	default:
+		return Undo{}, fmt.Errorf("error: you can only undo your own spawns and moves, and the last change was %s", last.Kind)
+	}
+
+	before := Replay(username, kept[:len(kept)-1], 0).GetPlayerSnap()
+	player := gs.GetPlayerSnap()
+	byLocation := map[Location][]Unit{}
+	for _, moved := range last.Units {
+		unit, ok := before.Units[moved.ID]
+		if _, alive := player.Units[moved.ID]; !ok || !alive {
+			continue
+		}
+		u.Units = append(u.Units, unit)
+		byLocation[unit.Location] = append(byLocation[unit.Location], unit)
+		player.Units[unit.ID] = unit
+	}
+	if len(u.Units) == 0 {
+		return Undo{}, fmt.Errorf("error: none of the units moved in event #%d are left", last.Seq)
+	}
+	locations := []Location{}
+	for loc := range byLocation {
+		locations = append(locations, loc)
+	}
+	sort.Slice(locations, func(i, j int) bool {
+		return locations[i] < locations[j]
+	})
+	for _, loc := range locations {
+		u.Moves = append(u.Moves, ArmyMove{
+			ToLocation: loc,
+			Units:      byLocation[loc],
+			Player:     player,
+			Turn:       turn,
+		})
+	}
+	return u, nil
+}
+
+// undoable drops the events that were undone, together with the undos
+// themselves.
+func undoable(events []Event) []Event {
+	kept := []Event{}
+	for _, e := range events {
+		if e.Kind == EventUndone {
+			if len(kept) > 0 {
+				kept = kept[:len(kept)-1]
+			}
+			continue
+		}
+		kept = append(kept, e)
+	}
+	return kept
+}
+
+func (gs *GameState) ApplyUndo(u Undo) {
+	gs.record(Event{
+		Kind:   EventUndone,
+		Units:  u.Units,
+		IDs:    u.Removed,
+		Undone: u.Seq,
+	})
+	fmt.Printf("Took back event #%d (%s)\n", u.Seq, u.Kind)
+}
+
+// UndoSync is the army the server has to hear about to take back a spawn.
+func (gs *GameState) UndoSync(u Undo) PlayerSync {
+	sync := gs.SpawnSync()
+	for _, id := range u.Removed {
+		delete(sync.Player.Units, id)
+	}
+	return sync
+}
@@ -0,0 +1,41 @@
+package gamelogic
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestUndoTakesBackTheLatestSpawnsAndMoves(t *testing.T) {
+	gs := NewGameState("napoleon")
+	require.NoError(t, gs.CommandSpawn([]string{"spawn", "europe", "infantry"}))
+	require.NoError(t, gs.CommandSpawn([]string{"spawn", "europe", "cavalry"}))
+	_, err := gs.CommandMove([]string{"move", "asia", "1", "2"})
+	require.NoError(t, err)
+	gold := gs.GetGold()
+
+	undo, err := gs.PlanUndo()
+	require.NoError(t, err)
+	require.Equal(t, EventUnitMoved, undo.Kind)
+	require.Len(t, undo.Moves, 1)
+	require.Equal(t, Location("europe"), undo.Moves[0].ToLocation)
+	require.Len(t, undo.Moves[0].Units, 2)
+	gs.ApplyUndo(undo)
+	require.Equal(t, Location("europe"), gs.GetPlayerSnap().Units[1].Location)
+	require.Equal(t, Location("europe"), gs.GetPlayerSnap().Units[2].Location)
+
+	undo, err = gs.PlanUndo()
+	require.NoError(t, err)
+	require.Equal(t, EventUnitSpawned, undo.Kind)
+	require.Equal(t, []int{2}, undo.Removed)
+	require.NotContains(t, gs.UndoSync(undo).Player.Units, 2)
+	gs.ApplyUndo(undo)
+	require.Len(t, gs.GetPlayerSnap().Units, 1)
+	require.Equal(t, gold, gs.GetGold(), "the server keeps the gold a spawn cost")
+
+	require.Equal(t, gs.GetPlayerSnap(), Replay("napoleon", gs.Events(), 0).GetPlayerSnap())
+
+	gs.HandleStateDelta(StateDelta{Username: "napoleon", Removed: []int{1}, Reason: "war war-1"})
+	_, err = gs.PlanUndo()
+	require.Error(t, err, "changes from the server can not be undone")
+}
@@ -0,0 +1,66 @@
+package gamelogic
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// allocateUnitID hands out per-player unit IDs that are never reused, even
+// after the units holding them are destroyed.
+func (gs *GameState) allocateUnitID() (int, error) {
+	gs.mu.Lock()
+	defer gs.mu.Unlock()
+	id := gs.NextUnitID
+	for {
+		if _, ok := gs.Player.Units[id]; !ok {
+			break
+		}
+		id++
+	}
+	if gs.unitCounterPath != "" {
+		err := writeUnitCounter(gs.unitCounterPath, id+1)
+		if err != nil {
+			return 0, err
+		}
+	}
+	gs.NextUnitID = id + 1
+	return id, nil
+}
+
+// PersistUnitCounter loads the unit ID counter from path, if it exists, and
+// saves it there after every allocation so restarts never reuse an ID.
+func (gs *GameState) PersistUnitCounter(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("could not read unit counter: %v", err)
+	}
+	gs.mu.Lock()
+	defer gs.mu.Unlock()
+	if err == nil {
+		next, err := strconv.Atoi(strings.TrimSpace(string(data)))
+		if err != nil {
+			return fmt.Errorf("unit counter %s is corrupt: %v", path, err)
+		}
+		if next > gs.NextUnitID {
+			gs.NextUnitID = next
+		}
+	}
+	gs.unitCounterPath = path
+	return writeUnitCounter(path, gs.NextUnitID)
+}
+
+func writeUnitCounter(path string, next int) error {
+	tmp := filepath.Join(filepath.Dir(path), "."+filepath.Base(path)+".tmp")
+	err := os.WriteFile(tmp, []byte(strconv.Itoa(next)+"\n"), 0644)
+	if err != nil {
+		return fmt.Errorf("could not save unit counter: %v", err)
+	}
+	err = os.Rename(tmp, path)
+	if err != nil {
+		return fmt.Errorf("could not save unit counter: %v", err)
+	}
+	return nil
+}
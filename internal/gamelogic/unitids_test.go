@@ -0,0 +1,44 @@
+package gamelogic
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"testing"
+
+	"github.com/bootdotdev/learn-pub-sub-starter/internal/schema"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSpawnNeverReusesDestroyedIDs(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "napoleon.units")
+	gs := NewGameState("napoleon")
+	require.NoError(t, gs.PersistUnitCounter(path))
+
+	require.NoError(t, gs.CommandSpawn([]string{"spawn", "europe", "infantry"}))
+	require.NoError(t, gs.CommandSpawn([]string{"spawn", "asia", "cavalry"}))
+	gs.record(Event{Kind: EventUnitsDestroyed, IDs: []int{1}})
+	require.NoError(t, gs.CommandSpawn([]string{"spawn", "europe", "artillery"}))
+
+	units := gs.GetPlayerSnap().Units
+	require.Len(t, units, 2)
+	require.Equal(t, UnitRank(RankCavalry), units[2].Rank)
+	require.Equal(t, UnitRank(RankArtillery), units[3].Rank)
+	require.Equal(t, "napoleon#3", units[3].Key())
+
+	restarted := NewGameState("napoleon")
+	require.NoError(t, restarted.PersistUnitCounter(path))
+	require.NoError(t, restarted.CommandSpawn([]string{"spawn", "europe", "infantry"}))
+	_, ok := restarted.GetUnit(4)
+	require.True(t, ok)
+}
+
+func TestArmyMoveV1IsUpcastWithOwners(t *testing.T) {
+	v1 := []byte(`{"Player":{"Username":"washington","Units":{"1":{"ID":1,"Rank":"infantry","Location":"europe"}}},"Units":[{"ID":1,"Rank":"infantry","Location":"europe"}],"ToLocation":"europe"}`)
+	body, err := schema.Upcast(ArmyMove{}.SchemaName(), 1, armyMoveVersion, "application/json", v1)
+	require.NoError(t, err)
+
+	mv := ArmyMove{}
+	require.NoError(t, json.Unmarshal(body, &mv))
+	require.Equal(t, "washington", mv.Units[0].Owner)
+	require.Equal(t, "washington", mv.Player.Units[1].Owner)
+}
@@ -0,0 +1,61 @@
+package gamelogic
+
+// VisibleLocations is everywhere the player can see: the territories they
+// occupy and the territories next to those.
+func (m *WorldMap) VisibleLocations(p Player) map[Location]struct{} {
+	visible := map[Location]struct{}{}
+	for _, unit := range p.Units {
+		visible[unit.Location] = struct{}{}
+		for _, n := range m.Neighbors(unit.Location) {
+			visible[n] = struct{}{}
+		}
+	}
+	return visible
+}
+
+// RedactMove cuts a move down to the units the viewer can see, and hides
+// the mover's gold. It reports false when the viewer sees none of the moved
+// units, in which case they should not hear about the move at all.
+func RedactMove(move ArmyMove, visible map[Location]struct{}) (ArmyMove, bool) {
+	units := []Unit{}
+	for _, unit := range move.Units {
+		if _, ok := visible[unit.Location]; ok {
+			units = append(units, unit)
+		}
+	}
+	if len(units) == 0 {
+		return ArmyMove{}, false
+	}
+	player := Player{
+		Username: move.Player.Username,
+		Units:    map[int]Unit{},
+	}
+	for id, unit := range move.Player.Units {
+		if _, ok := visible[unit.Location]; ok {
+			player.Units[id] = unit
+		}
+	}
+	return ArmyMove{
+		Player:     player,
+		Units:      units,
+		ToLocation: move.ToLocation,
+		Turn:       move.Turn,
+	}, true
+}
+
+// VisibleMoves redacts a move for every other player in the world, keyed
+// by username. Players who can not see any of the moved units are left out.
+func (w *World) VisibleMoves(move ArmyMove) map[string]ArmyMove {
+	m := w.scenario.Map()
+	moves := map[string]ArmyMove{}
+	for _, viewer := range w.Snapshot() {
+		if viewer.Username == move.Player.Username {
+			continue
+		}
+		redacted, ok := RedactMove(move, m.VisibleLocations(viewer))
+		if ok {
+			moves[viewer.Username] = redacted
+		}
+	}
+	return moves
+}
@@ -0,0 +1,57 @@
+package gamelogic
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestVisibleMovesAreRedacted(t *testing.T) {
+	world := NewWorld(DefaultScenario())
+	syncUnits(t, world, "napoleon", Unit{ID: 1, Rank: RankInfantry, Location: "europe"})
+	move := ArmyMove{
+		Player: Player{
+			Username: "washington",
+			Gold:     12,
+			Units: map[int]Unit{
+				1: {ID: 1, Owner: "washington", Rank: RankCavalry, Location: "asia"},
+				2: {ID: 2, Owner: "washington", Rank: RankArtillery, Location: "antarctica"},
+				3: {ID: 3, Owner: "washington", Rank: RankInfantry, Location: "australia"},
+			},
+		},
+		Units:      []Unit{{ID: 1, Owner: "washington", Rank: RankCavalry, Location: "asia"}},
+		ToLocation: "asia",
+	}
+	syncUnits(t, world, "washington", move.Player.Units[1], move.Player.Units[2], move.Player.Units[3])
+
+	moves := world.VisibleMoves(move)
+	require.Len(t, moves, 1, "the mover is not told about their own move")
+	seen := moves["napoleon"]
+	require.Equal(t, move.Units, seen.Units)
+	require.Equal(t, map[int]Unit{1: move.Player.Units[1]}, seen.Player.Units, "units out of sight are left out")
+	require.Zero(t, seen.Player.Gold)
+
+	hidden := ArmyMove{
+		Player:     move.Player,
+		Units:      []Unit{move.Player.Units[3]},
+		ToLocation: "australia",
+	}
+	require.Empty(t, world.VisibleMoves(hidden))
+}
+
+func TestHandleMoveOnlyShowsVisibleUnits(t *testing.T) {
+	gs := NewGameState("napoleon")
+	require.NoError(t, gs.CommandSpawn([]string{"spawn", "australia", "infantry"}))
+	enemy := Player{
+		Username: "washington",
+		Units: map[int]Unit{
+			1: {ID: 1, Owner: "washington", Rank: RankInfantry, Location: "europe"},
+		},
+	}
+	outcome := gs.HandleMove(ArmyMove{Player: enemy, Units: []Unit{enemy.Units[1]}, ToLocation: "europe"})
+	require.Equal(t, MoveOutComeSafe, outcome)
+
+	enemy.Units[1] = Unit{ID: 1, Owner: "washington", Rank: RankInfantry, Location: "australia"}
+	outcome = gs.HandleMove(ArmyMove{Player: enemy, Units: []Unit{enemy.Units[1]}, ToLocation: "australia"})
+	require.Equal(t, MoveOutcomeMakeWar, outcome)
+}
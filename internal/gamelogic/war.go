@@ -2,6 +2,7 @@ package gamelogic
 
 import (
 	"fmt"
+	"sort"
 )
 
 type WarOutcome int
@@ -14,92 +15,177 @@ const (
 	WarOutcomeDraw
 )
 
-func (gs *GameState) HandleWar(rw RecognitionOfWar) (outcome WarOutcome, winner string, loser string) {
+// WarResolution is decided once, by the server, and sent to both
+// combatants so that each removes its own casualties.
+type WarResolution struct {
+	ID            string
+	Attacker      string
+	Defender      string
+	Location      Location
+	AttackerUnits []Unit
+	DefenderUnits []Unit
+	Winner        string
+	Loser         string
+	Casualties    map[string][]int
+	Report        BattleReport
+}
+
+func (res WarResolution) IsDraw() bool {
+	return res.Winner == ""
+}
+
+// WarConfirmation is published by each combatant once it has applied a
+// resolution to its own GameState.
+type WarConfirmation struct {
+	WarID    string
+	Username string
+	Outcome  WarOutcome
+	Lost     []int
+	Token    string `json:",omitempty"`
+}
+
+func (c WarConfirmation) Sender() (string, string) {
+	return c.Username, c.Token
+}
+
+// ResolveWar fights the war described by rw in the location both players
+// have units in.
+func ResolveWar(resolver CombatResolver, id string, rw RecognitionOfWar) (WarResolution, error) {
+	overlappingLocation := getOverlappingLocation(rw.Attacker, rw.Defender)
+	if overlappingLocation == "" {
+		return WarResolution{}, fmt.Errorf("%s and %s have no units in the same location", rw.Attacker.Username, rw.Defender.Username)
+	}
+	return resolveWarAt(resolver, id, rw, overlappingLocation), nil
+}
+
+func resolveWarAt(resolver CombatResolver, id string, rw RecognitionOfWar, overlappingLocation Location) WarResolution {
+	battle := Battle{
+		ID:            id,
+		Location:      overlappingLocation,
+		Attacker:      rw.Attacker.Username,
+		Defender:      rw.Defender.Username,
+		AttackerUnits: unitsInLocation(rw.Attacker, overlappingLocation),
+		DefenderUnits: unitsInLocation(rw.Defender, overlappingLocation),
+	}
+	report := resolver.Resolve(battle)
+	res := WarResolution{
+		ID:            id,
+		Attacker:      battle.Attacker,
+		Defender:      battle.Defender,
+		Location:      battle.Location,
+		AttackerUnits: battle.AttackerUnits,
+		DefenderUnits: battle.DefenderUnits,
+		Winner:        report.Winner,
+		Casualties:    report.Casualties,
+		Report:        report,
+	}
+	switch report.Winner {
+	case res.Attacker:
+		res.Loser = res.Defender
+	case res.Defender:
+		res.Loser = res.Attacker
+	}
+	return res
+}
+
+func (gs *GameState) HandleWar(res WarResolution) (outcome WarOutcome, winner string, loser string) {
 	defer fmt.Println("------------------------")
 	fmt.Println()
 	fmt.Println("==== War Declared ====")
-	fmt.Printf("%s has declared war on %s!\n", rw.Attacker.Username, rw.Defender.Username)
+	fmt.Printf("%s has declared war on %s!\n", res.Attacker, res.Defender)
 
 	player := gs.GetPlayerSnap()
 
-	if player.Username == rw.Defender.Username {
-		fmt.Printf("%s, you published the war.\n", player.Username)
-		return WarOutcomeNotInvolved, "", ""
-	}
-
-	if player.Username != rw.Attacker.Username {
+	if player.Username != res.Attacker && player.Username != res.Defender {
 		fmt.Printf("%s, you are not involved in this war.\n", player.Username)
 		return WarOutcomeNotInvolved, "", ""
 	}
 
-	overlappingLocation := getOverlappingLocation(rw.Attacker, rw.Defender)
-	if overlappingLocation == "" {
+	if len(res.AttackerUnits) == 0 || len(res.DefenderUnits) == 0 {
 		fmt.Printf("Error! No units are in the same location. No war will be fought.\n")
 		return WarOutcomeNoUnits, "", ""
 	}
 
-	attackerUnits := []Unit{}
-	defenderUnits := []Unit{}
-	for _, unit := range rw.Attacker.Units {
-		if unit.Location == overlappingLocation {
-			attackerUnits = append(attackerUnits, unit)
-		}
+	fmt.Printf("%s's units:\n", res.Attacker)
+	for _, unit := range res.AttackerUnits {
+		fmt.Printf("  * %v\n", unit.Rank)
 	}
-	for _, unit := range rw.Defender.Units {
-		if unit.Location == overlappingLocation {
-			defenderUnits = append(defenderUnits, unit)
-		}
+	fmt.Printf("%s's units:\n", res.Defender)
+	for _, unit := range res.DefenderUnits {
+		fmt.Printf("  * %v\n", unit.Rank)
 	}
+	fmt.Printf("Attacker has a power level of %v\n", gs.Scenario().PowerLevel(res.AttackerUnits))
+	fmt.Printf("Defender has a power level of %v\n", gs.Scenario().PowerLevel(res.DefenderUnits))
+	printBattleReport(res)
 
-	fmt.Printf("%s's units:\n", rw.Attacker.Username)
-	for _, unit := range attackerUnits {
-		fmt.Printf("  * %v\n", unit.Rank)
+	lost := res.Casualties[player.Username]
+	if len(lost) > 0 {
+		gs.record(Event{Kind: EventUnitsDestroyed, IDs: lost, Reason: "war " + res.ID})
 	}
-	fmt.Printf("%s's units:\n", rw.Defender.Username)
-	for _, unit := range defenderUnits {
-		fmt.Printf("  * %v\n", unit.Rank)
+
+	if res.IsDraw() {
+		fmt.Println("The war ended in a draw!")
+		printLosses(res, player.Username, lost)
+		return WarOutcomeDraw, res.Attacker, res.Defender
+	}
+	fmt.Printf("%s has won the war!\n", res.Winner)
+	if res.Winner == player.Username {
+		printLosses(res, player.Username, lost)
+		return WarOutcomeYouWon, res.Winner, res.Loser
+	}
+	fmt.Println("You have lost the war!")
+	printLosses(res, player.Username, lost)
+	return WarOutcomeOpponentWon, res.Winner, res.Loser
+}
+
+func printBattleReport(res WarResolution) {
+	r := res.Report
+	if len(r.Rounds) == 0 {
+		return
 	}
-	attackerPower := unitsToPowerLevel(attackerUnits)
-	defenderPower := unitsToPowerLevel(defenderUnits)
-	fmt.Printf("Attacker has a power level of %v\n", attackerPower)
-	fmt.Printf("Defender has a power level of %v\n", defenderPower)
-	if attackerPower > defenderPower {
-		fmt.Printf("%s has won the war!\n", rw.Attacker.Username)
-		if player.Username == rw.Defender.Username {
-			fmt.Println("You have lost the war!")
-			gs.removeUnitsInLocation(overlappingLocation)
-			fmt.Printf("Your units in %s have been killed.\n", overlappingLocation)
-			return WarOutcomeOpponentWon, rw.Attacker.Username, rw.Defender.Username
+	fmt.Printf("The battle is fought on %s terrain; the defender gets +%d%%.\n", r.Terrain, r.DefenseBonus)
+	for _, round := range r.Rounds {
+		fmt.Printf("Round %d: %s rolled %v for %d damage, %s rolled %v for %d damage\n",
+			round.Number, res.Attacker, round.AttackerRolls, round.AttackerDamage, res.Defender, round.DefenderRolls, round.DefenderDamage)
+		for _, username := range []string{res.Attacker, res.Defender} {
+			if killed := round.Killed[username]; len(killed) > 0 {
+				fmt.Printf("  * %s lost units %v\n", username, killed)
+			}
 		}
-		return WarOutcomeYouWon, rw.Attacker.Username, rw.Defender.Username
-	} else if defenderPower > attackerPower {
-		fmt.Printf("%s has won the war!\n", rw.Defender.Username)
-		if player.Username == rw.Attacker.Username {
-			fmt.Println("You have lost the war!")
-			gs.removeUnitsInLocation(overlappingLocation)
-			fmt.Printf("Your units in %s have been killed.\n", overlappingLocation)
-			return WarOutcomeOpponentWon, rw.Defender.Username, rw.Attacker.Username
+	}
+}
+
+func printLosses(res WarResolution, username string, lost []int) {
+	mine := res.DefenderUnits
+	if username == res.Attacker {
+		mine = res.AttackerUnits
+	}
+	switch {
+	case len(lost) == 0:
+	case len(lost) == len(mine):
+		fmt.Printf("Your units in %s have been killed.\n", res.Location)
+	default:
+		fmt.Printf("You lost %d of your %d units in %s.\n", len(lost), len(mine), res.Location)
+	}
+}
+
+func unitsInLocation(p Player, loc Location) []Unit {
+	units := []Unit{}
+	for _, unit := range p.Units {
+		if unit.Location == loc {
+			units = append(units, unit)
 		}
-		return WarOutcomeYouWon, rw.Defender.Username, rw.Attacker.Username
 	}
-	fmt.Println("The war ended in a draw!")
-	fmt.Printf("Your units in %s have been killed.\n", overlappingLocation)
-	gs.removeUnitsInLocation(overlappingLocation)
-	return WarOutcomeDraw, rw.Attacker.Username, rw.Defender.Username
+	sort.Slice(units, func(i, j int) bool {
+		return units[i].ID < units[j].ID
+	})
+	return units
 }
 
-func unitsToPowerLevel(units []Unit) int {
-	power := 0
+func unitIDs(units []Unit) []int {
+	ids := []int{}
 	for _, unit := range units {
-		if unit.Rank == RankArtillery {
-			power += 10
-		}
-		if unit.Rank == RankCavalry {
-			power += 5
-		}
-		if unit.Rank == RankInfantry {
-			power += 1
-		}
+		ids = append(ids, unit.ID)
 	}
-	return power
+	return ids
 }
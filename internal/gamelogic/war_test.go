@@ -0,0 +1,111 @@
+package gamelogic
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newTestPlayer(username string, units ...Unit) *GameState {
+	gs := NewGameState(username)
+	gs.record(Event{Kind: EventUnitSpawned, Units: units})
+	return gs
+}
+
+func TestHandleWarAppliesOutcomeToBothSides(t *testing.T) {
+	tests := []struct {
+		name             string
+		attackerUnits    []Unit
+		defenderUnits    []Unit
+		attackerOutcome  WarOutcome
+		defenderOutcome  WarOutcome
+		attackerSurvives int
+		defenderSurvives int
+	}{
+		{
+			name:             "attacker wins",
+			attackerUnits:    []Unit{{ID: 1, Rank: RankArtillery, Location: "europe"}},
+			defenderUnits:    []Unit{{ID: 1, Rank: RankInfantry, Location: "europe"}, {ID: 2, Rank: RankInfantry, Location: "asia"}},
+			attackerOutcome:  WarOutcomeYouWon,
+			defenderOutcome:  WarOutcomeOpponentWon,
+			attackerSurvives: 1,
+			defenderSurvives: 1,
+		},
+		{
+			name:             "defender wins",
+			attackerUnits:    []Unit{{ID: 1, Rank: RankInfantry, Location: "europe"}, {ID: 2, Rank: RankCavalry, Location: "africa"}},
+			defenderUnits:    []Unit{{ID: 1, Rank: RankCavalry, Location: "europe"}},
+			attackerOutcome:  WarOutcomeOpponentWon,
+			defenderOutcome:  WarOutcomeYouWon,
+			attackerSurvives: 1,
+			defenderSurvives: 1,
+		},
+		{
+			name:             "draw",
+			attackerUnits:    []Unit{{ID: 1, Rank: RankCavalry, Location: "europe"}},
+			defenderUnits:    []Unit{{ID: 1, Rank: RankCavalry, Location: "europe"}, {ID: 2, Rank: RankArtillery, Location: "asia"}},
+			attackerOutcome:  WarOutcomeDraw,
+			defenderOutcome:  WarOutcomeDraw,
+			attackerSurvives: 0,
+			defenderSurvives: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			attacker := newTestPlayer("washington", tt.attackerUnits...)
+			defender := newTestPlayer("napoleon", tt.defenderUnits...)
+			res, err := ResolveWar(NewPowerResolver(DefaultScenario()), "war-1", RecognitionOfWar{
+				Attacker: attacker.GetPlayerSnap(),
+				Defender: defender.GetPlayerSnap(),
+			})
+			require.NoError(t, err)
+
+			outcome, _, _ := attacker.HandleWar(res)
+			require.Equal(t, tt.attackerOutcome, outcome)
+			require.Len(t, attacker.GetPlayerSnap().Units, tt.attackerSurvives)
+
+			outcome, _, _ = defender.HandleWar(res)
+			require.Equal(t, tt.defenderOutcome, outcome)
+			require.Len(t, defender.GetPlayerSnap().Units, tt.defenderSurvives)
+		})
+	}
+}
+
+func TestHandleWarIgnoresBystanders(t *testing.T) {
+	res, err := ResolveWar(NewPowerResolver(DefaultScenario()), "war-1", RecognitionOfWar{
+		Attacker: newTestPlayer("washington", Unit{ID: 1, Rank: RankArtillery, Location: "europe"}).GetPlayerSnap(),
+		Defender: newTestPlayer("napoleon", Unit{ID: 1, Rank: RankInfantry, Location: "europe"}).GetPlayerSnap(),
+	})
+	require.NoError(t, err)
+
+	bystander := newTestPlayer("wellington", Unit{ID: 1, Rank: RankInfantry, Location: "europe"})
+	outcome, _, _ := bystander.HandleWar(res)
+	require.Equal(t, WarOutcomeNotInvolved, outcome)
+	require.Len(t, bystander.GetPlayerSnap().Units, 1)
+}
+
+func TestHandleWarWithoutUnits(t *testing.T) {
+	attacker := newTestPlayer("washington")
+	defender := newTestPlayer("napoleon", Unit{ID: 1, Rank: RankInfantry, Location: "europe"})
+	res := WarResolution{
+		ID:            "war-1",
+		Attacker:      "washington",
+		Defender:      "napoleon",
+		Location:      "europe",
+		DefenderUnits: []Unit{{ID: 1, Rank: RankInfantry, Location: "europe"}},
+	}
+
+	outcome, _, _ := attacker.HandleWar(res)
+	require.Equal(t, WarOutcomeNoUnits, outcome)
+	outcome, _, _ = defender.HandleWar(res)
+	require.Equal(t, WarOutcomeNoUnits, outcome)
+	require.Len(t, defender.GetPlayerSnap().Units, 1)
+}
+
+func TestResolveWarRequiresOverlap(t *testing.T) {
+	_, err := ResolveWar(NewPowerResolver(DefaultScenario()), "war-1", RecognitionOfWar{
+		Attacker: newTestPlayer("washington", Unit{ID: 1, Rank: RankArtillery, Location: "americas"}).GetPlayerSnap(),
+		Defender: newTestPlayer("napoleon", Unit{ID: 1, Rank: RankInfantry, Location: "europe"}).GetPlayerSnap(),
+	})
+	require.Error(t, err)
+}
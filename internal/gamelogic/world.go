@@ -0,0 +1,456 @@
+package gamelogic
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/bootdotdev/learn-pub-sub-starter/internal/routing"
+)
+
+// PlayerSync tells the server about a player's full army, for example after
+// spawning units, which is otherwise never broadcast.
+type PlayerSync struct {
+	Player Player
+	Token  string `json:",omitempty"`
+}
+
+func (ps PlayerSync) Sender() (string, string) {
+	return ps.Player.Username, ps.Token
+}
+
+// StateDelta is the server's correction to one player's GameState.
+type StateDelta struct {
+	Seq      int
+	Username string
+	Units    []Unit
+	Removed  []int
+	Reason   string
+}
+
+func (d StateDelta) IsEmpty() bool {
+	return len(d.Units) == 0 && len(d.Removed) == 0
+}
+
+// World is the server's authoritative model of every player's units.
+type World struct {
+	players   map[string]Player
+	destroyed map[string]map[int]struct{}
+	wars      map[string]*pendingWar
+	seq       int
+	tick      int
+	warCount  int
+	turnBased bool
+	turn      routing.TurnState
+	queued    []ArmyMove
+	alliances *Alliances
+	mu        *sync.RWMutex
+	scenario  *Scenario
+}
+
+type pendingWar struct {
+	res       WarResolution
+	turn      int
+	confirmed map[string]bool
+}
+
+func NewWorld(sc *Scenario) *World {
+	return &World{
+		players:   map[string]Player{},
+		destroyed: map[string]map[int]struct{}{},
+		wars:      map[string]*pendingWar{},
+		alliances: NewAlliances(),
+		mu:        &sync.RWMutex{},
+		scenario:  sc,
+	}
+}
+
+// HandleMove validates a move against the model. A rejected move returns an
+// error together with a delta that puts the mover's units back where the
+// server has them. In turn-based games valid moves are queued until the turn
+// ends.
+func (w *World) HandleMove(move ArmyMove) (StateDelta, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.turnBased {
+		return w.queueMove(move)
+	}
+	return w.applyMove(move)
+}
+
+// applyMove moves the units in move.Units and nothing else; the rest of the
+// mover's army stays as the server has it.
+func (w *World) applyMove(move ArmyMove) (StateDelta, error) {
+	delta, err := w.checkMove(move)
+	if err != nil {
+		return delta, err
+	}
+	snapshot := copyPlayer(w.players[move.Player.Username])
+	snapshot.Username = move.Player.Username
+	for _, unit := range move.Units {
+		snapshot.Units[unit.ID] = unit
+	}
+	return w.merge(snapshot, "move accepted"), nil
+}
+
+func (w *World) checkMove(move ArmyMove) (StateDelta, error) {
+	username := move.Player.Username
+	if username == "" {
+		return StateDelta{}, errors.New("move has no player")
+	}
+	if _, ok := w.scenario.Map().Locations()[move.ToLocation]; !ok {
+		return w.correction(username, move.Units, "invalid location"), fmt.Errorf("%s is not a valid location", move.ToLocation)
+	}
+
+	known := w.players[username]
+	for _, unit := range move.Units {
+		if unit.Owner != username {
+			return w.correction(username, move.Units, "unit belongs to another player"), fmt.Errorf("unit %s does not belong to %s", unit.Key(), username)
+		}
+		if _, ok := w.destroyed[username][unit.ID]; ok {
+			return w.correction(username, move.Units, "unit was destroyed"), fmt.Errorf("unit %v was already destroyed", unit.ID)
+		}
+		snap, ok := move.Player.Units[unit.ID]
+		if !ok || snap.Location != move.ToLocation || unit.Location != move.ToLocation {
+			return w.correction(username, move.Units, "inconsistent move"), fmt.Errorf("unit %v is not in %s in the move's own snapshot", unit.ID, move.ToLocation)
+		}
+		if prev, ok := known.Units[unit.ID]; ok && prev.Rank != unit.Rank {
+			return w.correction(username, move.Units, "unit rank changed"), fmt.Errorf("unit %v changed rank from %s to %s", unit.ID, prev.Rank, unit.Rank)
+		}
+		if prev, ok := known.Units[unit.ID]; ok {
+			err := w.scenario.checkMove(prev, move.ToLocation)
+			if err != nil {
+				return w.correction(username, move.Units, "unit can not move that far"), err
+			}
+		}
+	}
+	expected := w.expectedUnits(username)
+	for id, snap := range move.Player.Units {
+		if moving(move, id) {
+			continue
+		}
+		if prev, ok := expected[id]; ok && (prev.Location != snap.Location || prev.Rank != snap.Rank) {
+			return w.correction(username, append(append([]Unit{}, move.Units...), snap), "snapshot does not match the server"), fmt.Errorf("unit %v is in %s in the move's snapshot but in %s on the server", id, snap.Location, prev.Location)
+		}
+	}
+	return StateDelta{}, nil
+}
+
+// expectedUnits is where the server expects a player's units to be: where
+// it has them, after any moves of theirs still queued for this turn.
+func (w *World) expectedUnits(username string) map[int]Unit {
+	units := copyPlayer(w.players[username]).Units
+	for _, queued := range w.queued {
+		if queued.Player.Username != username {
+			continue
+		}
+		for _, unit := range queued.Units {
+			units[unit.ID] = unit
+		}
+	}
+	return units
+}
+
+func moving(move ArmyMove, id int) bool {
+	for _, unit := range move.Units {
+		if unit.ID == id {
+			return true
+		}
+	}
+	return false
+}
+
+// HandleSync merges a client's full army into the model. Syncs add units but
+// never move them; units the server already knows stay where it has them.
+func (w *World) HandleSync(sync PlayerSync) (StateDelta, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if sync.Player.Username == "" {
+		return StateDelta{}, errors.New("sync has no player")
+	}
+	for _, unit := range sync.Player.Units {
+		if unit.Owner != sync.Player.Username {
+			return StateDelta{}, fmt.Errorf("unit %s does not belong to %s", unit.Key(), sync.Player.Username)
+		}
+		if _, ok := w.scenario.Map().Locations()[unit.Location]; !ok {
+			return StateDelta{}, fmt.Errorf("unit %v is in invalid location %s", unit.ID, unit.Location)
+		}
+		if _, ok := w.scenario.UnitType(unit.Rank); !ok {
+			return StateDelta{}, fmt.Errorf("unit %v has invalid rank %s", unit.ID, unit.Rank)
+		}
+	}
+	// Syncs only bring new units; units the server knows keep the place
+	// and rank it has for them.
+	snapshot := copyPlayer(sync.Player)
+	for id, unit := range snapshot.Units {
+		if known, ok := w.players[snapshot.Username].Units[id]; ok {
+			unit.Location = known.Location
+			unit.Rank = known.Rank
+			snapshot.Units[id] = unit
+		}
+	}
+	return w.merge(snapshot, "sync accepted"), nil
+}
+
+// merge replaces the model's view of a player with the snapshot, except
+// that units the server knows were destroyed stay destroyed, and new units
+// the player can not afford are refused. So are new units of a player the
+// server already knows in a turn-based game whose orders are closed; only a
+// player's first sync, when they join, may bring units in between turns.
+// Refused units are sent back so the client drops them too. Known units
+// missing from the snapshot are recorded as destroyed, so they can not come
+// back in a later sync.
+func (w *World) merge(snapshot Player, reason string) StateDelta {
+	known, seen := w.players[snapshot.Username]
+	player := Player{
+		Username: snapshot.Username,
+		Units:    map[int]Unit{},
+		Gold:     known.Gold,
+	}
+	if !seen {
+		player.Gold = w.scenario.startingBalance()
+	}
+	ids := []int{}
+	for id := range snapshot.Units {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	removed := []int{}
+	for _, id := range ids {
+		unit := snapshot.Units[id]
+		if _, ok := w.destroyed[snapshot.Username][id]; ok {
+			removed = append(removed, id)
+			continue
+		}
+		if _, ok := known.Units[id]; !ok {
+			if seen && w.turnBased && w.turn.Phase != routing.TurnPhaseOrders {
+				removed = append(removed, id)
+				reason = "new units can only be spawned while orders are open"
+				continue
+			}
+			cost := w.scenario.UnitCost(unit.Rank)
+			if cost > player.Gold {
+				removed = append(removed, id)
+				reason = "could not afford every new unit"
+				continue
+			}
+			player.Gold -= cost
+		}
+		player.Units[id] = unit
+	}
+	for id := range known.Units {
+		if _, ok := snapshot.Units[id]; !ok {
+			if w.destroyed[snapshot.Username] == nil {
+				w.destroyed[snapshot.Username] = map[int]struct{}{}
+			}
+			w.destroyed[snapshot.Username][id] = struct{}{}
+		}
+	}
+	w.players[snapshot.Username] = player
+	return w.delta(snapshot.Username, nil, removed, reason)
+}
+
+// RejectMove refuses a move without looking at it, for example because the
+// game is not running, and puts the mover's units back.
+func (w *World) RejectMove(move ArmyMove, reason string) StateDelta {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.correction(move.Player.Username, move.Units, reason)
+}
+
+func (w *World) correction(username string, units []Unit, reason string) StateDelta {
+	known := w.players[username]
+	restored := []Unit{}
+	removed := []int{}
+	for _, unit := range units {
+		if _, ok := w.destroyed[username][unit.ID]; ok {
+			removed = append(removed, unit.ID)
+			continue
+		}
+		if prev, ok := known.Units[unit.ID]; ok {
+			restored = append(restored, prev)
+		}
+	}
+	return w.delta(username, restored, removed, reason)
+}
+
+// RemoveUnits records units as destroyed, for example as war casualties.
+func (w *World) RemoveUnits(username string, ids []int, reason string) StateDelta {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.destroyed[username] == nil {
+		w.destroyed[username] = map[int]struct{}{}
+	}
+	for _, id := range ids {
+		w.destroyed[username][id] = struct{}{}
+		delete(w.players[username].Units, id)
+	}
+	return w.delta(username, nil, ids, reason)
+}
+
+// ResolveDeclaredWar fights a war a client declared. The declaration only
+// says who fights where; both armies come from the server's own model.
+func (w *World) ResolveDeclaredWar(resolver CombatResolver, rw RecognitionOfWar) (WarResolution, error) {
+	location := getOverlappingLocation(rw.Attacker, rw.Defender)
+	if location == "" {
+		return WarResolution{}, fmt.Errorf("%s and %s have no units in the same location", rw.Attacker.Username, rw.Defender.Username)
+	}
+	attacker, _ := w.GetPlayer(rw.Attacker.Username)
+	defender, _ := w.GetPlayer(rw.Defender.Username)
+	if len(unitsInLocation(attacker, location)) == 0 || len(unitsInLocation(defender, location)) == 0 {
+		return WarResolution{}, fmt.Errorf("%s and %s do not both hold units in %s", rw.Attacker.Username, rw.Defender.Username, location)
+	}
+	rw = RecognitionOfWar{Attacker: attacker, Defender: defender}
+	return resolveWarAt(resolver, w.NextWarID(), rw, location), nil
+}
+
+// NextWarID numbers wars in the order the server receives them, so that a
+// war's dice only depend on the seed and the message log.
+func (w *World) NextWarID() string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.warCount++
+	return fmt.Sprintf("war-%d", w.warCount)
+}
+
+// RecordWar applies a resolution's casualties to the model and waits for
+// both combatants to confirm it.
+func (w *World) RecordWar(res WarResolution) {
+	for username, ids := range res.Casualties {
+		w.RemoveUnits(username, ids, "war "+res.ID)
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.wars[res.ID] = &pendingWar{
+		res:       res,
+		turn:      w.turn.Turn,
+		confirmed: map[string]bool{},
+	}
+}
+
+// AbandonWars stops waiting for confirmations of the wars username fought,
+// for when they will never send them, and returns those wars.
+func (w *World) AbandonWars(username string) []WarResolution {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.dropWars(func(war *pendingWar) bool {
+		return war.res.Attacker == username || war.res.Defender == username
+	})
+}
+
+// dropWars forgets the pending wars drop matches, in the order they were
+// fought. The caller holds w.mu.
+func (w *World) dropWars(drop func(*pendingWar) bool) []WarResolution {
+	ids := []string{}
+	for id, war := range w.wars {
+		if drop(war) {
+			ids = append(ids, id)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		return warNumber(ids[i]) < warNumber(ids[j])
+	})
+	dropped := []WarResolution{}
+	for _, id := range ids {
+		dropped = append(dropped, w.wars[id].res)
+		delete(w.wars, id)
+	}
+	return dropped
+}
+
+func warNumber(id string) int {
+	var n int
+	fmt.Sscanf(id, "war-%d", &n)
+	return n
+}
+
+// ConfirmWar records one combatant's confirmation and reports whether the
+// war is now confirmed by both sides.
+func (w *World) ConfirmWar(c WarConfirmation) (WarResolution, bool, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	war, ok := w.wars[c.WarID]
+	if !ok {
+		return WarResolution{}, false, fmt.Errorf("unknown war %s", c.WarID)
+	}
+	if c.Username != war.res.Attacker && c.Username != war.res.Defender {
+		return WarResolution{}, false, fmt.Errorf("%s did not fight in war %s", c.Username, c.WarID)
+	}
+	war.confirmed[c.Username] = true
+	if !war.confirmed[war.res.Attacker] || !war.confirmed[war.res.Defender] {
+		return war.res, false, nil
+	}
+	delete(w.wars, c.WarID)
+	return war.res, true, nil
+}
+
+func (w *World) delta(username string, units []Unit, removed []int, reason string) StateDelta {
+	w.seq++
+	return StateDelta{
+		Seq:      w.seq,
+		Username: username,
+		Units:    units,
+		Removed:  removed,
+		Reason:   reason,
+	}
+}
+
+func (w *World) Alliances() *Alliances {
+	return w.alliances
+}
+
+func (w *World) GetPlayer(username string) (Player, bool) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	p, ok := w.players[username]
+	if !ok {
+		return Player{}, false
+	}
+	return copyPlayer(p), true
+}
+
+func (w *World) Snapshot() []Player {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	players := []Player{}
+	for _, p := range w.players {
+		players = append(players, copyPlayer(p))
+	}
+	sort.Slice(players, func(i, j int) bool {
+		return players[i].Username < players[j].Username
+	})
+	return players
+}
+
+func copyPlayer(p Player) Player {
+	units := map[int]Unit{}
+	for k, v := range p.Units {
+		units[k] = v
+	}
+	return Player{
+		Username: p.Username,
+		Units:    units,
+		Gold:     p.Gold,
+	}
+}
+
+func (w *World) PrintStatus() {
+	players := w.Snapshot()
+	if len(players) == 0 {
+		fmt.Println("No players have reported any units yet.")
+		return
+	}
+	for _, p := range players {
+		fmt.Printf("%s has %d gold and %d units:\n", p.Username, p.Gold, len(p.Units))
+		ids := []int{}
+		for id := range p.Units {
+			ids = append(ids, id)
+		}
+		sort.Ints(ids)
+		for _, id := range ids {
+			unit := p.Units[id]
+			fmt.Printf("* %v: %v, %v\n", unit.ID, unit.Location, unit.Rank)
+		}
+	}
+}
@@ -0,0 +1,126 @@
+package gamelogic
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWorldRejectsMovesOfDestroyedUnits(t *testing.T) {
+	world := NewWorld(DefaultScenario())
+	_, err := world.HandleSync(PlayerSync{Player: Player{
+		Username: "napoleon",
+		Units: map[int]Unit{
+			1: {ID: 1, Owner: "napoleon", Rank: RankInfantry, Location: "europe"},
+			2: {ID: 2, Owner: "napoleon", Rank: RankCavalry, Location: "europe"},
+		},
+	}})
+	require.NoError(t, err)
+	world.RemoveUnits("napoleon", []int{1}, "lost a war")
+
+	moved := Unit{ID: 1, Owner: "napoleon", Rank: RankInfantry, Location: "asia"}
+	delta, err := world.HandleMove(ArmyMove{
+		Player: Player{
+			Username: "napoleon",
+			Units: map[int]Unit{
+				1: moved,
+				2: {ID: 2, Owner: "napoleon", Rank: RankCavalry, Location: "europe"},
+			},
+		},
+		Units:      []Unit{moved},
+		ToLocation: "asia",
+	})
+	require.Error(t, err)
+	require.Equal(t, "napoleon", delta.Username)
+	require.Equal(t, []int{1}, delta.Removed)
+
+	player, ok := world.GetPlayer("napoleon")
+	require.True(t, ok)
+	require.Len(t, player.Units, 1)
+	require.Equal(t, Location("europe"), player.Units[2].Location)
+}
+
+func TestWorldDropsDestroyedUnitsFromSnapshots(t *testing.T) {
+	world := NewWorld(DefaultScenario())
+	world.RemoveUnits("washington", []int{3}, "lost a war")
+
+	delta, err := world.HandleSync(PlayerSync{Player: Player{
+		Username: "washington",
+		Units: map[int]Unit{
+			3: {ID: 3, Owner: "washington", Rank: RankArtillery, Location: "americas"},
+			4: {ID: 4, Owner: "washington", Rank: RankInfantry, Location: "americas"},
+		},
+	}})
+	require.NoError(t, err)
+	require.Equal(t, []int{3}, delta.Removed)
+
+	player, _ := world.GetPlayer("washington")
+	require.Len(t, player.Units, 1)
+}
+
+func TestWorldRejectsSnapshotsThatMoveOtherUnits(t *testing.T) {
+	world := NewWorld(DefaultScenario())
+	syncUnits(t, world, "napoleon",
+		Unit{ID: 1, Rank: RankInfantry, Location: "europe"},
+		Unit{ID: 2, Rank: RankArtillery, Location: "europe"},
+	)
+	move := moveTo("napoleon", 0, Unit{ID: 1, Rank: RankInfantry}, "asia")
+	move.Player.Units[2] = Unit{ID: 2, Owner: "napoleon", Rank: RankArtillery, Location: "australia"}
+
+	delta, err := world.HandleMove(move)
+	require.Error(t, err)
+	require.Contains(t, delta.Units, Unit{ID: 2, Owner: "napoleon", Rank: RankArtillery, Location: "europe"})
+	player, _ := world.GetPlayer("napoleon")
+	require.Equal(t, Location("europe"), player.Units[1].Location)
+	require.Equal(t, Location("europe"), player.Units[2].Location)
+
+	delete(move.Player.Units, 2)
+	_, err = world.HandleMove(move)
+	require.NoError(t, err)
+	player, _ = world.GetPlayer("napoleon")
+	require.Equal(t, Location("asia"), player.Units[1].Location)
+	require.Equal(t, Location("europe"), player.Units[2].Location, "units left out of the snapshot stay where they are")
+}
+
+func TestWorldKeepsItsRanksAndRecordsUnitsLeftOutOfSyncs(t *testing.T) {
+	world := NewWorld(DefaultScenario())
+	syncUnits(t, world, "napoleon",
+		Unit{ID: 1, Rank: RankInfantry, Location: "europe"},
+		Unit{ID: 2, Rank: RankInfantry, Location: "europe"},
+	)
+	before, _ := world.GetPlayer("napoleon")
+
+	syncUnits(t, world, "napoleon", Unit{ID: 1, Rank: RankArtillery, Location: "europe"})
+	player, _ := world.GetPlayer("napoleon")
+	require.Equal(t, UnitRank(RankInfantry), player.Units[1].Rank, "a sync can not promote a unit")
+	require.Equal(t, before.Gold, player.Gold)
+	require.NotContains(t, player.Units, 2)
+
+	delta, err := world.HandleSync(PlayerSync{Player: Player{Username: "napoleon", Units: map[int]Unit{
+		1: {ID: 1, Owner: "napoleon", Rank: RankInfantry, Location: "europe"},
+		2: {ID: 2, Owner: "napoleon", Rank: RankInfantry, Location: "europe"},
+	}}})
+	require.NoError(t, err)
+	require.Equal(t, []int{2}, delta.Removed, "a unit left out of a sync is gone for good")
+}
+
+func TestDeclaredWarsAreFoughtWithTheServersArmies(t *testing.T) {
+	world := NewWorld(DefaultScenario())
+	syncUnits(t, world, "napoleon", Unit{ID: 1, Rank: RankInfantry, Location: "europe"})
+	syncUnits(t, world, "washington", Unit{ID: 1, Rank: RankInfantry, Location: "europe"})
+	invented := Player{Username: "washington", Units: map[int]Unit{
+		1: {ID: 1, Owner: "washington", Rank: RankArtillery, Location: "europe"},
+		9: {ID: 9, Owner: "washington", Rank: RankArtillery, Location: "europe"},
+	}}
+	napoleon, _ := world.GetPlayer("napoleon")
+
+	res, err := world.ResolveDeclaredWar(NewPowerResolver(DefaultScenario()), RecognitionOfWar{Attacker: napoleon, Defender: invented})
+	require.NoError(t, err)
+	require.Equal(t, Location("europe"), res.Location)
+	require.Equal(t, []Unit{{ID: 1, Owner: "washington", Rank: RankInfantry, Location: "europe"}}, res.DefenderUnits)
+
+	invented.Units = map[int]Unit{1: {ID: 1, Owner: "washington", Rank: RankInfantry, Location: "asia"}}
+	napoleon.Units[1] = Unit{ID: 1, Owner: "napoleon", Rank: RankInfantry, Location: "asia"}
+	_, err = world.ResolveDeclaredWar(NewPowerResolver(DefaultScenario()), RecognitionOfWar{Attacker: napoleon, Defender: invented})
+	require.Error(t, err, "neither army is in asia on the server")
+}
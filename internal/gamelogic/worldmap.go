@@ -0,0 +1,132 @@
+package gamelogic
+
+import (
+	"container/heap"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// WorldMap is an undirected graph of locations weighted by the movement
+// points it takes to cross each edge.
+type WorldMap struct {
+	edges map[Location]map[Location]int
+}
+
+func NewWorldMap() *WorldMap {
+	return &WorldMap{
+		edges: map[Location]map[Location]int{},
+	}
+}
+
+func (m *WorldMap) AddLocation(loc Location) {
+	if m.edges[loc] == nil {
+		m.edges[loc] = map[Location]int{}
+	}
+}
+
+func (m *WorldMap) Connect(a, b Location, cost int) {
+	m.AddLocation(a)
+	m.AddLocation(b)
+	m.edges[a][b] = cost
+	m.edges[b][a] = cost
+}
+
+func (m *WorldMap) Locations() map[Location]struct{} {
+	locations := map[Location]struct{}{}
+	for loc := range m.edges {
+		locations[loc] = struct{}{}
+	}
+	return locations
+}
+
+func (m *WorldMap) Neighbors(loc Location) []Location {
+	neighbors := []Location{}
+	for n := range m.edges[loc] {
+		neighbors = append(neighbors, n)
+	}
+	sort.Slice(neighbors, func(i, j int) bool {
+		return neighbors[i] < neighbors[j]
+	})
+	return neighbors
+}
+
+func (m *WorldMap) IsAdjacent(a, b Location) bool {
+	_, ok := m.edges[a][b]
+	return ok
+}
+
+// ShortestPath returns the cheapest route from one location to another,
+// including both ends, and its total cost.
+func (m *WorldMap) ShortestPath(from, to Location) ([]Location, int, error) {
+	if _, ok := m.edges[from]; !ok {
+		return nil, 0, fmt.Errorf("error: %s is not a valid location", from)
+	}
+	if _, ok := m.edges[to]; !ok {
+		return nil, 0, fmt.Errorf("error: %s is not a valid location", to)
+	}
+
+	dist := map[Location]int{from: 0}
+	prev := map[Location]Location{}
+	queue := &pathQueue{{loc: from}}
+	for queue.Len() > 0 {
+		cur := heap.Pop(queue).(pathItem)
+		if cur.cost > dist[cur.loc] {
+			continue
+		}
+		if cur.loc == to {
+			break
+		}
+		for _, next := range m.Neighbors(cur.loc) {
+			cost := cur.cost + m.edges[cur.loc][next]
+			if d, ok := dist[next]; ok && d <= cost {
+				continue
+			}
+			dist[next] = cost
+			prev[next] = cur.loc
+			heap.Push(queue, pathItem{loc: next, cost: cost})
+		}
+	}
+
+	cost, ok := dist[to]
+	if !ok {
+		return nil, 0, fmt.Errorf("error: there is no route from %s to %s", from, to)
+	}
+	path := []Location{to}
+	for loc := to; loc != from; {
+		loc = prev[loc]
+		path = append([]Location{loc}, path...)
+	}
+	return path, cost, nil
+}
+
+func formatPath(path []Location) string {
+	words := make([]string, 0, len(path))
+	for _, loc := range path {
+		words = append(words, string(loc))
+	}
+	return strings.Join(words, " -> ")
+}
+
+type pathItem struct {
+	loc  Location
+	cost int
+}
+
+type pathQueue []pathItem
+
+func (q pathQueue) Len() int { return len(q) }
+func (q pathQueue) Less(i, j int) bool {
+	if q[i].cost == q[j].cost {
+		return q[i].loc < q[j].loc
+	}
+	return q[i].cost < q[j].cost
+}
+func (q pathQueue) Swap(i, j int) { q[i], q[j] = q[j], q[i] }
+func (q *pathQueue) Push(x any)   { *q = append(*q, x.(pathItem)) }
+func (q *pathQueue) Pop() any {
+	old := *q
+	item := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return item
+}
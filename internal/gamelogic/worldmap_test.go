@@ -0,0 +1,28 @@
+package gamelogic
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestShortestPathPrefersLand(t *testing.T) {
+	m := DefaultScenario().Map()
+	path, cost, err := m.ShortestPath("europe", "australia")
+	require.NoError(t, err)
+	require.Equal(t, []Location{"europe", "asia", "australia"}, path)
+	require.Equal(t, 3, cost)
+}
+
+func TestMoveRespectsRankSpeed(t *testing.T) {
+	gs := NewGameState("napoleon")
+	require.NoError(t, gs.CommandSpawn([]string{"spawn", "europe", "artillery"}))
+	require.NoError(t, gs.CommandSpawn([]string{"spawn", "europe", "cavalry"}))
+
+	_, err := gs.CommandMove([]string{"move", "australia", "1"})
+	require.EqualError(t, err, "error: artillery 1 can move 2 but europe -> asia -> australia costs 3")
+
+	mv, err := gs.CommandMove([]string{"move", "australia", "2"})
+	require.NoError(t, err)
+	require.Equal(t, Location("australia"), mv.Units[0].Location)
+}
@@ -0,0 +1,274 @@
+package outbox
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"sync"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+const (
+	opPending = "pending"
+	opSent    = "sent"
+)
+
+type Message struct {
+	ID              string         `json:"id"`
+	Exchange        string         `json:"exchange"`
+	Key             string         `json:"key"`
+	ContentType     string         `json:"content_type"`
+	ContentEncoding string         `json:"content_encoding,omitempty"`
+	Headers         map[string]any `json:"headers,omitempty"`
+	Body            []byte         `json:"body"`
+}
+
+type record struct {
+	Op      string   `json:"op"`
+	ID      string   `json:"id,omitempty"`
+	Message *Message `json:"message,omitempty"`
+}
+
+// Outbox is an append-only file of messages that must reach the broker.
+// A message is recorded before the state change it describes is applied,
+// and stays pending until a relay has published it.
+type Outbox struct {
+	path    string
+	f       *os.File
+	mu      *sync.Mutex
+	pending []Message
+	notify  chan struct{}
+}
+
+func NewMessage(exchange, key string, msg amqp.Publishing) Message {
+	return Message{
+		Exchange:        exchange,
+		Key:             key,
+		ContentType:     msg.ContentType,
+		ContentEncoding: msg.ContentEncoding,
+		Headers:         msg.Headers,
+		Body:            msg.Body,
+	}
+}
+
+func (m Message) Publishing() amqp.Publishing {
+	headers := amqp.Table{}
+	for k, v := range m.Headers {
+		// JSON numbers come back as float64; keep whole numbers integers.
+		if f, ok := v.(float64); ok && f == math.Trunc(f) {
+			v = int64(f)
+		}
+		headers[k] = v
+	}
+	return amqp.Publishing{
+		MessageId:       m.ID,
+		ContentType:     m.ContentType,
+		ContentEncoding: m.ContentEncoding,
+		Headers:         headers,
+		Body:            m.Body,
+	}
+}
+
+// Open replays the file at path, keeping every message that was never marked
+// sent, and compacts the file down to just those messages.
+func Open(path string) (*Outbox, error) {
+	pending, err := replay(path)
+	if err != nil {
+		return nil, err
+	}
+	err = compact(path, pending)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("could not open outbox: %v", err)
+	}
+	return &Outbox{
+		path:    path,
+		f:       f,
+		mu:      &sync.Mutex{},
+		pending: pending,
+		notify:  make(chan struct{}, 1),
+	}, nil
+}
+
+func replay(path string) ([]Message, error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return []Message{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not open outbox: %v", err)
+	}
+	defer f.Close()
+
+	order := []string{}
+	byID := map[string]Message{}
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		rec := record{}
+		err := json.Unmarshal(scanner.Bytes(), &rec)
+		if err != nil {
+			// A crash mid-write leaves a torn last line; nothing after it
+			// was ever applied.
+			log.Printf("Skipping unreadable outbox record in %s: %v", path, err)
+			continue
+		}
+		switch rec.Op {
+		case opPending:
+			if rec.Message == nil {
+				continue
+			}
+			order = append(order, rec.Message.ID)
+			byID[rec.Message.ID] = *rec.Message
+		case opSent:
+			delete(byID, rec.ID)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("could not read outbox: %v", err)
+	}
+
+	pending := []Message{}
+	for _, id := range order {
+		if m, ok := byID[id]; ok {
+			pending = append(pending, m)
+		}
+	}
+	return pending, nil
+}
+
+func compact(path string, pending []Message) error {
+	tmp := path + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("could not compact outbox: %v", err)
+	}
+	for i := range pending {
+		err = writeRecord(f, record{Op: opPending, Message: &pending[i]})
+		if err != nil {
+			f.Close()
+			return fmt.Errorf("could not compact outbox: %v", err)
+		}
+	}
+	err = f.Sync()
+	if err != nil {
+		f.Close()
+		return fmt.Errorf("could not compact outbox: %v", err)
+	}
+	f.Close()
+	return os.Rename(tmp, path)
+}
+
+func writeRecord(f *os.File, rec record) error {
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(append(data, '\n'))
+	return err
+}
+
+// Commit durably records msgs and only then calls apply, so a state change
+// is never visible without the messages that announce it.
+func (o *Outbox) Commit(apply func(), msgs ...Message) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	for i := range msgs {
+		if msgs[i].ID == "" {
+			msgs[i].ID = newID()
+		}
+		err := writeRecord(o.f, record{Op: opPending, Message: &msgs[i]})
+		if err != nil {
+			return fmt.Errorf("could not write to outbox: %v", err)
+		}
+	}
+	err := o.f.Sync()
+	if err != nil {
+		return fmt.Errorf("could not sync outbox: %v", err)
+	}
+	if apply != nil {
+		apply()
+	}
+	o.pending = append(o.pending, msgs...)
+	select {
+	case o.notify <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+func (o *Outbox) Pending() []Message {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return append([]Message{}, o.pending...)
+}
+
+// Flush publishes pending messages in order, stopping at the first failure
+// so that later messages never overtake earlier ones.
+func (o *Outbox) Flush(publish func(Message) error) (int, error) {
+	sent := 0
+	for {
+		o.mu.Lock()
+		if len(o.pending) == 0 {
+			o.mu.Unlock()
+			return sent, nil
+		}
+		m := o.pending[0]
+		o.mu.Unlock()
+
+		err := publish(m)
+		if err != nil {
+			return sent, fmt.Errorf("could not publish %s: %v", m.ID, err)
+		}
+
+		o.mu.Lock()
+		err = writeRecord(o.f, record{Op: opSent, ID: m.ID})
+		if err == nil {
+			o.pending = o.pending[1:]
+		}
+		o.mu.Unlock()
+		if err != nil {
+			return sent, fmt.Errorf("could not mark %s as sent: %v", m.ID, err)
+		}
+		sent++
+	}
+}
+
+// Relay flushes whenever a message is committed, retrying failed publishes
+// every interval. It never returns.
+func (o *Outbox) Relay(publish func(Message) error, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-o.notify:
+		case <-ticker.C:
+		}
+		_, err := o.Flush(publish)
+		if err != nil {
+			log.Printf("Outbox relay: %v", err)
+		}
+	}
+}
+
+func (o *Outbox) Close() error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.f.Close()
+}
+
+func newID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,51 @@
+package outbox
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestReopenKeepsUnsentMessages(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "napoleon.outbox")
+	box, err := Open(path)
+	require.NoError(t, err)
+
+	applied := 0
+	err = box.Commit(func() { applied++ },
+		Message{Exchange: "peril_topic", Key: "army_moves.napoleon", Body: []byte("first")},
+		Message{Exchange: "peril_topic", Key: "army_moves.napoleon", Body: []byte("second")},
+	)
+	require.NoError(t, err)
+	require.Equal(t, 1, applied)
+
+	published := []string{}
+	sent, err := box.Flush(func(m Message) error {
+		if string(m.Body) == "second" {
+			return errors.New("broker went away")
+		}
+		published = append(published, string(m.Body))
+		return nil
+	})
+	require.Error(t, err)
+	require.Equal(t, 1, sent)
+	require.Equal(t, []string{"first"}, published)
+	require.NoError(t, box.Close())
+
+	// Simulate a crash in the middle of writing the next record.
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	require.NoError(t, err)
+	_, err = f.WriteString(`{"op":"pend`)
+	require.NoError(t, err)
+	f.Close()
+
+	box, err = Open(path)
+	require.NoError(t, err)
+	defer box.Close()
+	pending := box.Pending()
+	require.Len(t, pending, 1)
+	require.Equal(t, "second", string(pending[0].Body))
+}
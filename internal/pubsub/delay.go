@@ -0,0 +1,289 @@
+package pubsub
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/bootdotdev/learn-pub-sub-starter/internal/schema"
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// Delayed messages wait in a queue per delay whose TTL dead-letters them into
+// the delayed exchange. A scheduler consumes them from there and forwards them
+// to their real destination unless they were cancelled in the meantime.
+const (
+	delayedExchange  = "peril_delayed"
+	delayQueuePrefix = "peril_delay."
+	readyQueue       = "peril_delayed.ready"
+	readyKey         = "ready"
+	cancelKey        = "cancel"
+
+	headerDelayExchange = "x-delay-exchange"
+	headerDelayKey      = "x-delay-key"
+	headerDelayAttempts = "x-delay-attempts"
+
+	// Idle delay queues are deleted by the broker after this long.
+	delayQueueExpiry = time.Minute
+
+	// A message that can not be forwarded goes back through a delay queue,
+	// waiting twice as long after every attempt, and is dropped after
+	// maxForwardAttempts.
+	forwardRetryDelay  = time.Second
+	maxForwardAttempts = 5
+)
+
+// deathHeaders are added by the broker when a delay queue dead-letters a
+// message; they are not part of the message that was scheduled.
+var deathHeaders = []string{"x-death", "x-first-death-exchange", "x-first-death-queue", "x-first-death-reason", "x-last-death-exchange", "x-last-death-queue", "x-last-death-reason"}
+
+type Scheduler struct {
+	ch        *amqp.Channel
+	mu        *sync.Mutex
+	pending   map[string]time.Time
+	cancelled map[string]time.Time
+}
+
+func NewScheduler(conn *amqp.Connection) (*Scheduler, error) {
+	ch, err := conn.Channel()
+	if err != nil {
+		return nil, err
+	}
+	err = ch.ExchangeDeclare(delayedExchange, "direct", true, false, false, false, nil)
+	if err != nil {
+		return nil, fmt.Errorf("could not declare delayed exchange: %v", err)
+	}
+	_, err = ch.QueueDeclare(readyQueue, true, false, false, false, nil)
+	if err != nil {
+		return nil, fmt.Errorf("could not declare ready queue: %v", err)
+	}
+	err = ch.QueueBind(readyQueue, readyKey, delayedExchange, false, nil)
+	if err != nil {
+		return nil, fmt.Errorf("could not bind ready queue: %v", err)
+	}
+
+	s := &Scheduler{
+		ch:        ch,
+		mu:        &sync.Mutex{},
+		pending:   map[string]time.Time{},
+		cancelled: map[string]time.Time{},
+	}
+
+	consumeCh, err := conn.Channel()
+	if err != nil {
+		return nil, err
+	}
+	// Every scheduler hears every cancellation, since any of them may be the
+	// one that receives the expired message.
+	cancelQueue, err := consumeCh.QueueDeclare("", false, true, true, false, nil)
+	if err != nil {
+		return nil, fmt.Errorf("could not declare cancel queue: %v", err)
+	}
+	err = consumeCh.QueueBind(cancelQueue.Name, cancelKey, delayedExchange, false, nil)
+	if err != nil {
+		return nil, fmt.Errorf("could not bind cancel queue: %v", err)
+	}
+	cancels, err := consumeCh.Consume(cancelQueue.Name, "", true, true, false, false, nil)
+	if err != nil {
+		return nil, err
+	}
+	ready, err := consumeCh.Consume(readyQueue, "", false, false, false, false, nil)
+	if err != nil {
+		return nil, err
+	}
+	go func() {
+		for d := range cancels {
+			s.markCancelled(string(d.Body))
+		}
+	}()
+	go func() {
+		for d := range ready {
+			s.forward(d)
+		}
+	}()
+	return s, nil
+}
+
+func (s *Scheduler) PublishAt(exchange, key string, at time.Time, msg amqp.Publishing) (string, error) {
+	return s.PublishAfter(exchange, key, time.Until(at), msg)
+}
+
+func (s *Scheduler) PublishAfter(exchange, key string, delay time.Duration, msg amqp.Publishing) (string, error) {
+	if msg.MessageId == "" {
+		msg.MessageId = newMessageID()
+	}
+	if delay < time.Millisecond {
+		delay = time.Millisecond
+	}
+
+	headers := amqp.Table{}
+	for k, v := range msg.Headers {
+		headers[k] = v
+	}
+	headers[headerDelayExchange] = exchange
+	headers[headerDelayKey] = key
+	msg.Headers = headers
+	msg.DeliveryMode = amqp.Persistent
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	queue, err := s.declareDelayQueue(delay)
+	if err != nil {
+		return "", err
+	}
+	err = s.ch.PublishWithContext(context.Background(), "", queue, false, false, msg)
+	if err != nil {
+		return "", err
+	}
+	s.pending[msg.MessageId] = time.Now().Add(delay)
+	return msg.MessageId, nil
+}
+
+func (s *Scheduler) declareDelayQueue(delay time.Duration) (string, error) {
+	ttl := delay.Milliseconds()
+	name := fmt.Sprintf("%s%d", delayQueuePrefix, ttl)
+	_, err := s.ch.QueueDeclare(name, true, false, false, false, amqp.Table{
+		"x-message-ttl":             ttl,
+		"x-expires":                 ttl + delayQueueExpiry.Milliseconds(),
+		"x-dead-letter-exchange":    delayedExchange,
+		"x-dead-letter-routing-key": readyKey,
+	})
+	if err != nil {
+		return "", fmt.Errorf("could not declare delay queue: %v", err)
+	}
+	return name, nil
+}
+
+// Cancel stops a delayed message from being delivered. Cancellations are
+// only remembered by running schedulers, so a message whose delay expires
+// while no scheduler is running is still delivered.
+func (s *Scheduler) Cancel(id string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.pending[id]; !ok {
+		return false
+	}
+	err := s.ch.PublishWithContext(context.Background(), delayedExchange, cancelKey, false, false, amqp.Publishing{
+		ContentType: "text/plain",
+		Body:        []byte(id),
+	})
+	if err != nil {
+		log.Printf("Could not broadcast cancellation of %s: %v", id, err)
+	}
+	delete(s.pending, id)
+	s.cancelled[id] = time.Now()
+	return true
+}
+
+func (s *Scheduler) markCancelled(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.pending, id)
+	s.cancelled[id] = time.Now()
+}
+
+func (s *Scheduler) forward(d amqp.Delivery) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pruneCancelled()
+	delete(s.pending, d.MessageId)
+	if _, ok := s.cancelled[d.MessageId]; ok {
+		log.Printf("Dropped cancelled delayed message %s", d.MessageId)
+		delete(s.cancelled, d.MessageId)
+		d.Ack(false)
+		return
+	}
+
+	exchange, _ := d.Headers[headerDelayExchange].(string)
+	key, _ := d.Headers[headerDelayKey].(string)
+	err := s.ch.PublishWithContext(context.Background(), exchange, key, false, false, amqp.Publishing{
+		Headers:         copyHeaders(d.Headers, append(deathHeaders, headerDelayExchange, headerDelayKey, headerDelayAttempts)...),
+		ContentType:     d.ContentType,
+		ContentEncoding: d.ContentEncoding,
+		MessageId:       d.MessageId,
+		Body:            d.Body,
+	})
+	if err != nil {
+		log.Printf("Could not forward delayed message %s: %v", d.MessageId, err)
+		s.retry(d)
+		return
+	}
+	d.Ack(false)
+}
+
+// retry sends a message that could not be forwarded back through a delay
+// queue, so that a broken destination is not retried in a tight loop. The
+// caller holds s.mu.
+func (s *Scheduler) retry(d amqp.Delivery) {
+	attempts, _ := schema.HeaderInt(d.Headers[headerDelayAttempts])
+	attempts++
+	if attempts >= maxForwardAttempts {
+		log.Printf("Dropped delayed message %s after %d attempts to forward it", d.MessageId, attempts)
+		d.Nack(false, false)
+		return
+	}
+	delay := forwardRetryDelay << (attempts - 1)
+	headers := copyHeaders(d.Headers, deathHeaders...)
+	headers[headerDelayAttempts] = int32(attempts)
+	queue, err := s.declareDelayQueue(delay)
+	if err == nil {
+		err = s.ch.PublishWithContext(context.Background(), "", queue, false, false, amqp.Publishing{
+			Headers:         headers,
+			ContentType:     d.ContentType,
+			ContentEncoding: d.ContentEncoding,
+			MessageId:       d.MessageId,
+			DeliveryMode:    amqp.Persistent,
+			Body:            d.Body,
+		})
+	}
+	if err != nil {
+		log.Printf("Dropped delayed message %s: could not schedule another attempt: %v", d.MessageId, err)
+		d.Nack(false, false)
+		return
+	}
+	s.pending[d.MessageId] = time.Now().Add(delay)
+	d.Ack(false)
+}
+
+func copyHeaders(headers amqp.Table, skip ...string) amqp.Table {
+	copied := amqp.Table{}
+	for k, v := range headers {
+		copied[k] = v
+	}
+	for _, k := range skip {
+		delete(copied, k)
+	}
+	return copied
+}
+
+// pruneCancelled forgets cancellations old enough that their message has
+// long since expired elsewhere.
+func (s *Scheduler) pruneCancelled() {
+	for id, at := range s.cancelled {
+		if time.Since(at) > 24*time.Hour {
+			delete(s.cancelled, id)
+		}
+	}
+}
+
+func PublishJSONAfter[T any](s *Scheduler, exchange, key string, delay time.Duration, val T) (string, error) {
+	msg, err := NewJSONPublishing(val)
+	if err != nil {
+		return "", err
+	}
+	return s.PublishAfter(exchange, key, delay, msg)
+}
+
+func PublishJSONAt[T any](s *Scheduler, exchange, key string, at time.Time, val T) (string, error) {
+	return PublishJSONAfter(s, exchange, key, time.Until(at), val)
+}
+
+func newMessageID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
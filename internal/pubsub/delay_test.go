@@ -0,0 +1,89 @@
+package pubsub_test
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/bootdotdev/learn-pub-sub-starter/internal/config"
+	"github.com/bootdotdev/learn-pub-sub-starter/internal/pubsub"
+	amqp "github.com/rabbitmq/amqp091-go"
+	"github.com/stretchr/testify/require"
+)
+
+// dial connects to the broker the game uses, and skips the test when there
+// is none to connect to.
+func dial(t *testing.T) *amqp.Connection {
+	url := os.Getenv(config.EnvURL)
+	if url == "" {
+		url = config.DefaultURL
+	}
+	conn, err := amqp.Dial(url)
+	if err != nil {
+		t.Skipf("no broker at %s: %v", url, err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+// listen declares a queue bound to key on amq.direct and returns its
+// deliveries.
+func listen(t *testing.T, conn *amqp.Connection, key string) <-chan amqp.Delivery {
+	ch, err := conn.Channel()
+	require.NoError(t, err)
+	t.Cleanup(func() { ch.Close() })
+	queue, err := ch.QueueDeclare("", false, true, true, false, nil)
+	require.NoError(t, err)
+	require.NoError(t, ch.QueueBind(queue.Name, key, "amq.direct", false, nil))
+	deliveries, err := ch.Consume(queue.Name, "", true, true, false, false, nil)
+	require.NoError(t, err)
+	return deliveries
+}
+
+func TestPublishAfterDeliversOnceTheDelayHasPassed(t *testing.T) {
+	conn := dial(t)
+	s, err := pubsub.NewScheduler(conn)
+	require.NoError(t, err)
+	deliveries := listen(t, conn, t.Name())
+
+	start := time.Now()
+	id, err := pubsub.PublishJSONAfter(s, "amq.direct", t.Name(), 500*time.Millisecond, "hello")
+	require.NoError(t, err)
+
+	select {
+	case d := <-deliveries:
+		require.GreaterOrEqual(t, time.Since(start), 500*time.Millisecond)
+		require.Equal(t, id, d.MessageId)
+		require.Equal(t, `"hello"`, string(d.Body))
+		require.NotContains(t, d.Headers, "x-delay-exchange")
+		require.NotContains(t, d.Headers, "x-death")
+	case <-time.After(10 * time.Second):
+		t.Fatal("delayed message was never delivered")
+	}
+}
+
+func TestCancelStopsADelayedMessage(t *testing.T) {
+	conn := dial(t)
+	s, err := pubsub.NewScheduler(conn)
+	require.NoError(t, err)
+	deliveries := listen(t, conn, t.Name())
+
+	cancelled, err := pubsub.PublishJSONAfter(s, "amq.direct", t.Name(), 500*time.Millisecond, "cancelled")
+	require.NoError(t, err)
+	kept, err := pubsub.PublishJSONAfter(s, "amq.direct", t.Name(), time.Second, "kept")
+	require.NoError(t, err)
+	require.True(t, s.Cancel(cancelled))
+	require.False(t, s.Cancel(cancelled), "a message can only be cancelled once")
+
+	select {
+	case d := <-deliveries:
+		require.Equal(t, kept, d.MessageId)
+	case <-time.After(10 * time.Second):
+		t.Fatal("the message that was not cancelled was never delivered")
+	}
+	select {
+	case d := <-deliveries:
+		t.Fatalf("unexpected delivery of %s", d.MessageId)
+	case <-time.After(500 * time.Millisecond):
+	}
+}
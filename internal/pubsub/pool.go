@@ -0,0 +1,119 @@
+package pubsub
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// Publisher is satisfied by both a single *amqp.Channel and a ChannelPool.
+type Publisher interface {
+	PublishWithContext(ctx context.Context, exchange, key string, mandatory, immediate bool, msg amqp.Publishing) error
+}
+
+var ErrPoolClosed = errors.New("channel pool is closed")
+
+// ChannelPool hands out publishing channels so that no two goroutines ever
+// publish on the same channel at once. Consumers keep their own channels.
+type ChannelPool struct {
+	conn   *amqp.Connection
+	idle   chan *amqp.Channel
+	slots  chan struct{}
+	mu     *sync.Mutex
+	closed bool
+}
+
+func NewChannelPool(conn *amqp.Connection, size int) *ChannelPool {
+	if size < 1 {
+		size = 1
+	}
+	return &ChannelPool{
+		conn:  conn,
+		idle:  make(chan *amqp.Channel, size),
+		slots: make(chan struct{}, size),
+		mu:    &sync.Mutex{},
+	}
+}
+
+// Get blocks until fewer than size channels are checked out. Channels that
+// the broker closed while idle are dropped and replaced.
+func (p *ChannelPool) Get() (*amqp.Channel, error) {
+	p.mu.Lock()
+	closed := p.closed
+	p.mu.Unlock()
+	if closed {
+		return nil, ErrPoolClosed
+	}
+
+	p.slots <- struct{}{}
+	for {
+		select {
+		case ch := <-p.idle:
+			if ch.IsClosed() {
+				continue
+			}
+			return ch, nil
+		default:
+			ch, err := p.conn.Channel()
+			if err != nil {
+				<-p.slots
+				return nil, err
+			}
+			return ch, nil
+		}
+	}
+}
+
+func (p *ChannelPool) Put(ch *amqp.Channel) {
+	defer func() { <-p.slots }()
+	p.mu.Lock()
+	closed := p.closed
+	p.mu.Unlock()
+	if closed || ch.IsClosed() {
+		ch.Close()
+		return
+	}
+	select {
+	case p.idle <- ch:
+	default:
+		ch.Close()
+	}
+}
+
+// PublishWithContext publishes on a pooled channel. If the broker closed the
+// channel because of the publish, it is retried once on a fresh channel.
+func (p *ChannelPool) PublishWithContext(ctx context.Context, exchange, key string, mandatory, immediate bool, msg amqp.Publishing) error {
+	var err error
+	for attempt := 0; attempt < 2; attempt++ {
+		var ch *amqp.Channel
+		ch, err = p.Get()
+		if err != nil {
+			return err
+		}
+		err = ch.PublishWithContext(ctx, exchange, key, mandatory, immediate, msg)
+		p.Put(ch)
+		if err == nil || !ch.IsClosed() {
+			return err
+		}
+	}
+	return err
+}
+
+func (p *ChannelPool) Close() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		return
+	}
+	p.closed = true
+	for {
+		select {
+		case ch := <-p.idle:
+			ch.Close()
+		default:
+			return
+		}
+	}
+}
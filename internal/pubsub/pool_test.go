@@ -0,0 +1,51 @@
+package pubsub_test
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bootdotdev/learn-pub-sub-starter/internal/pubsub"
+	"github.com/stretchr/testify/require"
+)
+
+// Run with -race: every goroutine publishes through the same pool, which
+// must never hand one channel to two of them at once.
+func TestChannelPoolPublishesConcurrently(t *testing.T) {
+	conn := dial(t)
+	deliveries := listen(t, conn, t.Name())
+	pool := pubsub.NewChannelPool(conn, 4)
+	defer pool.Close()
+
+	const publishers, each = 16, 25
+	wg := &sync.WaitGroup{}
+	errs := make(chan error, publishers*each)
+	for i := 0; i < publishers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < each; j++ {
+				errs <- pubsub.PublishJSON(pool, "amq.direct", t.Name(), fmt.Sprintf("%d-%d", i, j))
+			}
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		require.NoError(t, err)
+	}
+
+	seen := map[string]bool{}
+	for len(seen) < publishers*each {
+		select {
+		case d := <-deliveries:
+			seen[string(d.Body)] = true
+		case <-time.After(10 * time.Second):
+			t.Fatalf("received %d of %d messages", len(seen), publishers*each)
+		}
+	}
+
+	pool.Close()
+	require.ErrorIs(t, pubsub.PublishJSON(pool, "amq.direct", t.Name(), "late"), pubsub.ErrPoolClosed)
+}
@@ -5,8 +5,10 @@ import (
 	"context"
 	"encoding/gob"
 	"encoding/json"
+	"fmt"
 	"log"
 
+	"github.com/bootdotdev/learn-pub-sub-starter/internal/schema"
 	amqp "github.com/rabbitmq/amqp091-go"
 )
 
@@ -25,8 +27,8 @@ const (
 	TransientQueue
 )
 
-func PublishJSON[T any](ch *amqp.Channel, exchange, key string, val T) error {
-	valjson, err := json.Marshal(val)
+func PublishJSON[T any](ch Publisher, exchange, key string, val T) error {
+	msg, err := NewJSONPublishing(val)
 	if err != nil {
 		return err
 	}
@@ -36,10 +38,7 @@ func PublishJSON[T any](ch *amqp.Channel, exchange, key string, val T) error {
 		key,
 		false,
 		false,
-		amqp.Publishing{
-			ContentType: "application/json",
-			Body:        valjson,
-		},
+		msg,
 	)
 	if err != nil {
 		return err
@@ -47,7 +46,23 @@ func PublishJSON[T any](ch *amqp.Channel, exchange, key string, val T) error {
 	return nil
 }
 
-func PublishGob[T any](ch *amqp.Channel, exchange, key string, val T) error {
+func Publish(ch Publisher, exchange, key string, msg amqp.Publishing) error {
+	return ch.PublishWithContext(context.Background(), exchange, key, false, false, msg)
+}
+
+func NewJSONPublishing[T any](val T) (amqp.Publishing, error) {
+	valjson, err := json.Marshal(val)
+	if err != nil {
+		return amqp.Publishing{}, err
+	}
+	return amqp.Publishing{
+		ContentType: "application/json",
+		Headers:     schemaHeaders(val),
+		Body:        valjson,
+	}, nil
+}
+
+func PublishGob[T any](ch Publisher, exchange, key string, val T) error {
 	var network bytes.Buffer
 	enc := gob.NewEncoder(&network)
 	err := enc.Encode(val)
@@ -63,6 +78,7 @@ func PublishGob[T any](ch *amqp.Channel, exchange, key string, val T) error {
 		amqp.Publishing{
 			ContentType:     "application/gob",
 			ContentEncoding: "binary",
+			Headers:         schemaHeaders(val),
 			Body:            network.Bytes(),
 		},
 	)
@@ -72,6 +88,41 @@ func PublishGob[T any](ch *amqp.Channel, exchange, key string, val T) error {
 	return nil
 }
 
+func schemaHeaders(val any) amqp.Table {
+	v, ok := val.(schema.Versioned)
+	if !ok {
+		return nil
+	}
+	return amqp.Table{
+		schema.HeaderName:    v.SchemaName(),
+		schema.HeaderVersion: int32(v.SchemaVersion()),
+	}
+}
+
+// upcast brings a delivery's body up to the version T is declared at.
+// Messages without a version header predate versioning.
+func upcast[T any](d amqp.Delivery) ([]byte, error) {
+	var zero T
+	v, ok := any(zero).(schema.Versioned)
+	if !ok {
+		return d.Body, nil
+	}
+	if name, ok := d.Headers[schema.HeaderName].(string); ok && name != v.SchemaName() {
+		return nil, fmt.Errorf("expected %s, got %s", v.SchemaName(), name)
+	}
+	version := schema.LegacyVersion
+	if raw, ok := d.Headers[schema.HeaderVersion]; ok {
+		version, ok = schema.HeaderInt(raw)
+		if !ok {
+			return nil, fmt.Errorf("invalid schema version %v", raw)
+		}
+	}
+	if version == v.SchemaVersion() {
+		return d.Body, nil
+	}
+	return schema.Upcast(v.SchemaName(), version, v.SchemaVersion(), d.ContentType, d.Body)
+}
+
 func DeclareAndBind(
 	conn *amqp.Connection,
 	exchange,
@@ -119,45 +170,52 @@ func subscribe[T any](
 	queueName,
 	key string,
 	simpleQueueType SimpleQueueType,
-	handler func(T) AckType,
+	handler func(string, T) AckType,
 	unmarshaller func([]byte) (T, error),
-) error {
-	ch, _, err := DeclareAndBind(conn, exchange, queueName, key, simpleQueueType)
+	opts ...SubscribeOption,
+) (*Subscription, error) {
+	sub := newSubscription(queueName, opts)
+	consume := func() (*consumer, error) {
+		return startConsumer(conn, exchange, queueName, key, simpleQueueType)
+	}
+	c, err := consume()
 	if err != nil {
-		return err
+		return nil, err
 	}
-	ch.Qos(10, 0, false)
-	retrnch, err := ch.Consume(queueName, "", false, false, false, false, nil)
+	go sub.run(conn, c, consume, func(_ *consumer, d amqp.Delivery) {
+		handleDelivery(d, queueName, handler, unmarshaller)
+	})
+	return sub, nil
+}
+
+func handleDelivery[T any](i amqp.Delivery, queueName string, handler func(string, T) AckType, unmarshaller func([]byte) (T, error)) {
+	body, err := upcast[T](i)
 	if err != nil {
-		return err
+		log.Printf("Could not upcast message from %s: %v", queueName, err)
+		i.Nack(false, false)
+		return
+	}
+	val, err := unmarshaller(body)
+	if err != nil {
+		log.Printf("Could not decode message from %s: %v", queueName, err)
+		i.Nack(false, false)
+		return
+	}
+	handlerreturn := handler(i.RoutingKey, val)
+	switch handlerreturn {
+	case Ack:
+		log.Printf("Received Ack")
+		err = i.Ack(false)
+	case NackRequeue:
+		log.Printf("Received NackRequeue")
+		err = i.Nack(false, true)
+	case NackDiscard:
+		log.Printf("Received NackDiscard")
+		err = i.Nack(false, false)
+	}
+	if err != nil {
+		log.Printf("Could not acknowledge message from %s: %v", queueName, err)
 	}
-	go func() {
-		for i := range retrnch {
-			val, err := unmarshaller(i.Body)
-			handlerreturn := handler(val)
-			switch handlerreturn {
-			case Ack:
-				log.Printf("Received Ack")
-				err = i.Ack(false)
-				if err != nil {
-					return
-				}
-			case NackRequeue:
-				log.Printf("Received NackRequeue")
-				i.Nack(false, true)
-				if err != nil {
-					return
-				}
-			case NackDiscard:
-				log.Printf("Received NackDiscard")
-				i.Nack(false, false)
-				if err != nil {
-					return
-				}
-			}
-		}
-	}()
-	return nil
 }
 
 func SubscribeGob[T any](
@@ -167,8 +225,9 @@ func SubscribeGob[T any](
 	key string,
 	simpleQueueType SimpleQueueType, // an enum to represent "durable" or "transient"
 	handler func(T) AckType,
-) error {
-	return subscribe(conn, exchange, queueName, key, simpleQueueType, handler, unmarshalGob[T])
+	opts ...SubscribeOption,
+) (*Subscription, error) {
+	return subscribe(conn, exchange, queueName, key, simpleQueueType, ignoreKey(handler), unmarshalGob[T], opts...)
 }
 
 func unmarshalGob[T any](data []byte) (T, error) {
@@ -188,8 +247,29 @@ func SubscribeJSON[T any](
 	key string,
 	simpleQueueType SimpleQueueType, // an enum to represent "durable" or "transient"
 	handler func(T) AckType,
-) error {
-	return subscribe(conn, exchange, queueName, key, simpleQueueType, handler, unmarshalJSON[T])
+	opts ...SubscribeOption,
+) (*Subscription, error) {
+	return subscribe(conn, exchange, queueName, key, simpleQueueType, ignoreKey(handler), unmarshalJSON[T], opts...)
+}
+
+// SubscribeJSONWithKey is SubscribeJSON for handlers that need the routing
+// key each message was published with, for example to tell who sent it.
+func SubscribeJSONWithKey[T any](
+	conn *amqp.Connection,
+	exchange,
+	queueName,
+	key string,
+	simpleQueueType SimpleQueueType, // an enum to represent "durable" or "transient"
+	handler func(string, T) AckType,
+	opts ...SubscribeOption,
+) (*Subscription, error) {
+	return subscribe(conn, exchange, queueName, key, simpleQueueType, handler, unmarshalJSON[T], opts...)
+}
+
+func ignoreKey[T any](handler func(T) AckType) func(string, T) AckType {
+	return func(_ string, val T) AckType {
+		return handler(val)
+	}
 }
 
 func unmarshalJSON[T any](data []byte) (T, error) {
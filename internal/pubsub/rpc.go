@@ -0,0 +1,126 @@
+package pubsub
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// Replies use RabbitMQ's direct reply-to, so callers need no queue of their
+// own.
+const directReplyTo = "amq.rabbitmq.reply-to"
+
+var (
+	ErrNoResponder = errors.New("nobody is serving requests")
+	ErrNoReply     = errors.New("no reply")
+)
+
+// CallJSON publishes req and waits for the reply. Requests are mandatory, so
+// a call fails straight away when no queue is bound to key.
+func CallJSON[Req, Resp any](conn *amqp.Connection, exchange, key string, req Req, timeout time.Duration) (Resp, error) {
+	var resp Resp
+	ch, err := conn.Channel()
+	if err != nil {
+		return resp, err
+	}
+	defer ch.Close()
+	replies, err := ch.Consume(directReplyTo, "", true, true, false, false, nil)
+	if err != nil {
+		return resp, fmt.Errorf("could not consume replies: %v", err)
+	}
+	returns := ch.NotifyReturn(make(chan amqp.Return, 1))
+
+	msg, err := NewJSONPublishing(req)
+	if err != nil {
+		return resp, err
+	}
+	msg.ReplyTo = directReplyTo
+	msg.CorrelationId = newMessageID()
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	err = ch.PublishWithContext(ctx, exchange, key, true, false, msg)
+	if err != nil {
+		return resp, err
+	}
+
+	for {
+		select {
+		case d, ok := <-replies:
+			if !ok {
+				return resp, fmt.Errorf("channel closed while waiting for a reply to %s", key)
+			}
+			if d.CorrelationId != msg.CorrelationId {
+				continue
+			}
+			body, err := upcast[Resp](d)
+			if err != nil {
+				return resp, err
+			}
+			return unmarshalJSON[Resp](body)
+		case <-returns:
+			return resp, fmt.Errorf("%w on %s", ErrNoResponder, key)
+		case <-ctx.Done():
+			return resp, fmt.Errorf("%w to %s within %v", ErrNoReply, key, timeout)
+		}
+	}
+}
+
+// ServeJSON answers requests made with CallJSON. The queue is exclusive to
+// this process, so callers notice when it is gone.
+func ServeJSON[Req, Resp any](
+	conn *amqp.Connection,
+	exchange,
+	queueName,
+	key string,
+	handler func(Req) Resp,
+	opts ...SubscribeOption,
+) (*Subscription, error) {
+	sub := newSubscription(queueName, opts)
+	consume := func() (*consumer, error) {
+		return startConsumer(conn, exchange, queueName, key, TransientQueue)
+	}
+	c, err := consume()
+	if err != nil {
+		return nil, err
+	}
+	go sub.run(conn, c, consume, func(c *consumer, d amqp.Delivery) {
+		reply(c.ch, d, queueName, handler)
+	})
+	return sub, nil
+}
+
+func reply[Req, Resp any](ch *amqp.Channel, d amqp.Delivery, queueName string, handler func(Req) Resp) {
+	if d.ReplyTo == "" {
+		log.Printf("Discarding request from %s without a reply-to", queueName)
+		d.Nack(false, false)
+		return
+	}
+	body, err := upcast[Req](d)
+	if err != nil {
+		log.Printf("Could not upcast request from %s: %v", queueName, err)
+		d.Nack(false, false)
+		return
+	}
+	req, err := unmarshalJSON[Req](body)
+	if err != nil {
+		log.Printf("Could not decode request from %s: %v", queueName, err)
+		d.Nack(false, false)
+		return
+	}
+	msg, err := NewJSONPublishing(handler(req))
+	if err != nil {
+		log.Printf("Could not encode reply to %s: %v", queueName, err)
+		d.Nack(false, false)
+		return
+	}
+	msg.CorrelationId = d.CorrelationId
+	err = ch.PublishWithContext(context.Background(), "", d.ReplyTo, false, false, msg)
+	if err != nil {
+		log.Printf("Could not reply to request from %s: %v", queueName, err)
+	}
+	d.Ack(false)
+}
@@ -0,0 +1,149 @@
+package pubsub
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+var ErrConsumerCancelled = errors.New("consumer cancelled by the broker")
+
+const resumeRetryDelay = 2 * time.Second
+
+// Subscription reports why consuming stopped, for example because the queue
+// was deleted in the management UI. Errors is closed once the subscription
+// has stopped for good, and Err then tells why.
+type Subscription struct {
+	Queue     string
+	errs      chan error
+	err       error
+	redeclare bool
+}
+
+type SubscribeOption func(*Subscription)
+
+// WithRedeclare makes the subscription declare and bind its queue again and
+// resume consuming after the broker cancels the consumer or closes its
+// channel.
+func WithRedeclare() SubscribeOption {
+	return func(s *Subscription) {
+		s.redeclare = true
+	}
+}
+
+func newSubscription(queueName string, opts []SubscribeOption) *Subscription {
+	sub := &Subscription{
+		Queue: queueName,
+		errs:  make(chan error, 8),
+	}
+	for _, opt := range opts {
+		opt(sub)
+	}
+	return sub
+}
+
+func (s *Subscription) Errors() <-chan error {
+	return s.errs
+}
+
+// Err returns the error that stopped the subscription for good, or nil if
+// it was closed on purpose. It is only meaningful once Errors is closed.
+func (s *Subscription) Err() error {
+	return s.err
+}
+
+// report never blocks; errors nobody reads are only logged.
+func (s *Subscription) report(err error) {
+	select {
+	case s.errs <- err:
+	default:
+	}
+}
+
+func (s *Subscription) resume(conn *amqp.Connection, consume func() (*consumer, error)) *consumer {
+	for {
+		if conn.IsClosed() {
+			return nil
+		}
+		time.Sleep(resumeRetryDelay)
+		c, err := consume()
+		if err == nil {
+			log.Printf("Re-declared %s and resumed consuming", s.Queue)
+			return c
+		}
+		log.Printf("Could not resume consuming from %s: %v", s.Queue, err)
+		s.report(err)
+	}
+}
+
+// run hands every delivery to handle, resuming after the broker stops the
+// consumer if the subscription asks for it, until it stops for good.
+func (s *Subscription) run(conn *amqp.Connection, c *consumer, consume func() (*consumer, error), handle func(*consumer, amqp.Delivery)) {
+	defer close(s.errs)
+	for {
+		for d := range c.deliveries {
+			handle(c, d)
+		}
+		err := c.stopped()
+		if err == nil {
+			return
+		}
+		log.Printf("Stopped consuming from %s: %v", s.Queue, err)
+		s.report(err)
+		if !s.redeclare {
+			s.err = err
+			return
+		}
+		c = s.resume(conn, consume)
+		if c == nil {
+			s.err = err
+			return
+		}
+	}
+}
+
+type consumer struct {
+	queue      string
+	ch         *amqp.Channel
+	deliveries <-chan amqp.Delivery
+	cancels    chan string
+	closes     chan *amqp.Error
+}
+
+func startConsumer(conn *amqp.Connection, exchange, queueName, key string, simpleQueueType SimpleQueueType) (*consumer, error) {
+	ch, _, err := DeclareAndBind(conn, exchange, queueName, key, simpleQueueType)
+	if err != nil {
+		return nil, err
+	}
+	ch.Qos(10, 0, false)
+	c := &consumer{
+		queue:   queueName,
+		ch:      ch,
+		cancels: ch.NotifyCancel(make(chan string, 1)),
+		closes:  ch.NotifyClose(make(chan *amqp.Error, 1)),
+	}
+	c.deliveries, err = ch.Consume(queueName, "", false, false, false, false, nil)
+	if err != nil {
+		ch.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+// stopped explains why the deliveries channel closed. It returns nil when
+// the channel or connection was closed on purpose.
+func (c *consumer) stopped() error {
+	select {
+	case tag := <-c.cancels:
+		c.ch.Close()
+		return fmt.Errorf("%w: queue %s, consumer %s", ErrConsumerCancelled, c.queue, tag)
+	case amqpErr, ok := <-c.closes:
+		if !ok || amqpErr == nil {
+			return nil
+		}
+		return fmt.Errorf("channel for queue %s closed: %v", c.queue, amqpErr)
+	}
+}
@@ -11,3 +11,140 @@ type GameLog struct {
 	Message     string
 	Username    string
 }
+
+// ScenarioAnnouncement tells every client which scenario the server is
+// running, so clients that loaded a different one can stop.
+type ScenarioAnnouncement struct {
+	Name string
+	Hash string
+}
+
+// JoinRequest is the handshake a client makes before it starts playing.
+// An empty Game joins DefaultGame. Returning players send the Token they
+// were given to reclaim their username.
+type JoinRequest struct {
+	Game         string
+	Username     string
+	Token        string
+	ScenarioName string
+	ScenarioHash string
+}
+
+// JoinResponse carries the game's seed so clients make the same random
+// choices a replay of the game would.
+type JoinResponse struct {
+	Accepted     bool
+	Reason       string
+	Token        string
+	ScenarioName string
+	ScenarioHash string
+	Seed         int64
+	TurnBased    bool
+	Turn         TurnState
+	Phase        string
+}
+
+const (
+	TurnPhaseOrders    = "orders"
+	TurnPhaseResolving = "resolving"
+)
+
+// TurnState announces the turn in turn-based games. Clients may only give
+// orders while Phase is TurnPhaseOrders and Deadline has not passed.
+type TurnState struct {
+	Turn     int
+	Phase    string
+	Deadline time.Time
+}
+
+const (
+	GamePhaseLobby    = "lobby"
+	GamePhaseRunning  = "running"
+	GamePhasePaused   = "paused"
+	GamePhaseFinished = "finished"
+)
+
+// GameStatus announces every change in the game's lifecycle.
+type GameStatus struct {
+	Phase string
+}
+
+// GameSummary is what the lobby shows about a game.
+type GameSummary struct {
+	ID           string
+	ScenarioName string
+	Phase        string
+	TurnBased    bool
+	Players      int
+}
+
+type ListGamesRequest struct{}
+
+type GameList struct {
+	Games []GameSummary
+}
+
+// CreateGameRequest asks the server to host a new game of the scenario the
+// client loaded.
+type CreateGameRequest struct {
+	Game         string
+	Username     string
+	ScenarioName string
+	ScenarioHash string
+}
+
+type CreateGameResponse struct {
+	Accepted bool
+	Reason   string
+	Game     GameSummary
+}
+
+// Heartbeat tells the server a client is still playing. Leaving is set once,
+// when the player quits.
+type Heartbeat struct {
+	Username string
+	SentAt   time.Time
+	Leaving  bool
+	Token    string `json:",omitempty"`
+}
+
+func (hb Heartbeat) Sender() (string, string) {
+	return hb.Username, hb.Token
+}
+
+const (
+	PresenceOnline     = "online"
+	PresenceAFK        = "afk"
+	PresenceLeft       = "left"
+	PresenceEliminated = "eliminated"
+)
+
+// PresenceEvent announces a player joining, going quiet, leaving or being
+// eliminated for staying away too long.
+type PresenceEvent struct {
+	Username string
+	Status   string
+	At       time.Time
+}
+
+const (
+	DiplomacyPropose = "propose"
+	DiplomacyAccept  = "accept"
+	DiplomacyBreak   = "break"
+)
+
+// Diplomacy is one player proposing, accepting or breaking an alliance with
+// another. Allies who share a territory do not go to war. Players sign
+// what they send the server with their session Token; the server clears it
+// before passing the message on.
+type Diplomacy struct {
+	From   string
+	To     string
+	Action string
+	At     time.Time
+	Token  string `json:",omitempty"`
+}
+
+func (d Diplomacy) Sender() (string, string) {
+	return d.From, d.Token
+}
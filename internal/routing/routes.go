@@ -0,0 +1,346 @@
+package routing
+
+const (
+	ExchangeTypeDirect = "direct"
+	ExchangeTypeTopic  = "topic"
+)
+
+const (
+	ContentTypeJSON = "application/json"
+	ContentTypeGob  = "application/gob"
+)
+
+// Route describes one kind of message on the broker. Keys and queue names
+// use {game} and {username} for the parts filled in at runtime. Requests name the
+// payload sent back to the caller's reply-to queue in Reply.
+type Route struct {
+	Name         string
+	Description  string
+	Exchange     string
+	ExchangeType string
+	Key          string
+	BindingKey   string
+	Queue        string
+	Durable      bool
+	Payload      string
+	ContentType  string
+	Publisher    string
+	Subscriber   string
+	Reply        string
+}
+
+func Routes() []Route {
+	return []Route{
+		{
+			Name:         PauseKey,
+			Description:  "The server pauses and resumes a game for every client playing it.",
+			Exchange:     ExchangePerilDirect,
+			ExchangeType: ExchangeTypeDirect,
+			Key:          PauseKey + ".{game}",
+			BindingKey:   PauseKey + ".{game}",
+			Queue:        PauseKey + ".{game}.{username}",
+			Durable:      false,
+			Payload:      PlayingState{}.SchemaName(),
+			ContentType:  ContentTypeJSON,
+			Publisher:    "server",
+			Subscriber:   "client",
+		},
+		{
+			Name:         ScenarioKey,
+			Description:  "The server announces the scenario it is running; clients with a different one stop.",
+			Exchange:     ExchangePerilDirect,
+			ExchangeType: ExchangeTypeDirect,
+			Key:          ScenarioKey,
+			BindingKey:   ScenarioKey,
+			Queue:        ScenarioKey + ".{game}.{username}",
+			Durable:      false,
+			Payload:      ScenarioAnnouncement{}.SchemaName(),
+			ContentType:  ContentTypeJSON,
+			Publisher:    "server",
+			Subscriber:   "client",
+		},
+		{
+			Name:         JoinKey,
+			Description:  "A client asks to join a game; the server refuses clients running a different scenario.",
+			Exchange:     ExchangePerilDirect,
+			ExchangeType: ExchangeTypeDirect,
+			Key:          JoinKey,
+			BindingKey:   JoinKey,
+			Queue:        JoinKey,
+			Durable:      false,
+			Payload:      JoinRequest{}.SchemaName(),
+			ContentType:  ContentTypeJSON,
+			Publisher:    "client",
+			Subscriber:   "server",
+			Reply:        JoinResponse{}.SchemaName(),
+		},
+		{
+			Name:         ListGamesKey,
+			Description:  "A client in the lobby asks which games the server is hosting.",
+			Exchange:     ExchangePerilDirect,
+			ExchangeType: ExchangeTypeDirect,
+			Key:          ListGamesKey,
+			BindingKey:   ListGamesKey,
+			Queue:        ListGamesKey,
+			Durable:      false,
+			Payload:      ListGamesRequest{}.SchemaName(),
+			ContentType:  ContentTypeJSON,
+			Publisher:    "client",
+			Subscriber:   "server",
+			Reply:        GameList{}.SchemaName(),
+		},
+		{
+			Name:         CreateGameKey,
+			Description:  "A client in the lobby asks the server to host a new game.",
+			Exchange:     ExchangePerilDirect,
+			ExchangeType: ExchangeTypeDirect,
+			Key:          CreateGameKey,
+			BindingKey:   CreateGameKey,
+			Queue:        CreateGameKey,
+			Durable:      false,
+			Payload:      CreateGameRequest{}.SchemaName(),
+			ContentType:  ContentTypeJSON,
+			Publisher:    "client",
+			Subscriber:   "server",
+			Reply:        CreateGameResponse{}.SchemaName(),
+		},
+		{
+			Name:         EconomyKey,
+			Description:  "The server pays income for controlled territories and sends each player their own income and balance.",
+			Exchange:     ExchangePerilDirect,
+			ExchangeType: ExchangeTypeDirect,
+			Key:          EconomyKey + ".{game}.{username}",
+			BindingKey:   EconomyKey + ".{game}.{username}",
+			Queue:        EconomyKey + ".{game}.{username}",
+			Durable:      false,
+			Payload:      "economy_tick",
+			ContentType:  ContentTypeJSON,
+			Publisher:    "server",
+			Subscriber:   "client",
+		},
+		{
+			Name:         TurnKey,
+			Description:  "In turn-based games the server opens each turn's orders and closes them to resolve the turn.",
+			Exchange:     ExchangePerilDirect,
+			ExchangeType: ExchangeTypeDirect,
+			Key:          TurnKey + ".{game}",
+			BindingKey:   TurnKey + ".{game}",
+			Queue:        TurnKey + ".{game}.{username}",
+			Durable:      false,
+			Payload:      TurnState{}.SchemaName(),
+			ContentType:  ContentTypeJSON,
+			Publisher:    "server",
+			Subscriber:   "client",
+		},
+		{
+			Name:         GameStatusKey,
+			Description:  "The server announces every change in the game's lifecycle: lobby, running, paused and finished.",
+			Exchange:     ExchangePerilDirect,
+			ExchangeType: ExchangeTypeDirect,
+			Key:          GameStatusKey + ".{game}",
+			BindingKey:   GameStatusKey + ".{game}",
+			Queue:        GameStatusKey + ".{game}.{username}",
+			Durable:      false,
+			Payload:      GameStatus{}.SchemaName(),
+			ContentType:  ContentTypeJSON,
+			Publisher:    "server",
+			Subscriber:   "client",
+		},
+		{
+			Name:         GameOverKey,
+			Description:  "The server announces the winner and the final standings.",
+			Exchange:     ExchangePerilDirect,
+			ExchangeType: ExchangeTypeDirect,
+			Key:          GameOverKey + ".{game}",
+			BindingKey:   GameOverKey + ".{game}",
+			Queue:        GameOverKey + ".{game}.{username}",
+			Durable:      false,
+			Payload:      "game_over",
+			ContentType:  ContentTypeJSON,
+			Publisher:    "server",
+			Subscriber:   "client",
+		},
+		{
+			Name:         WorldPauseQueue,
+			Description:  "The server follows its own pause and resume messages, including scheduled ones, to track the game's lifecycle.",
+			Exchange:     ExchangePerilDirect,
+			ExchangeType: ExchangeTypeDirect,
+			Key:          PauseKey + ".{game}",
+			BindingKey:   PauseKey + ".{game}",
+			Queue:        WorldPauseQueue + ".{game}",
+			Durable:      false,
+			Payload:      PlayingState{}.SchemaName(),
+			ContentType:  ContentTypeJSON,
+			Publisher:    "server",
+			Subscriber:   "server",
+		},
+		{
+			Name:         VisibleMovesPrefix,
+			Description:  "The server passes a move on to every player who can see some of the moved units, redacted to the units in or next to their territories; they check it for overlapping armies.",
+			Exchange:     ExchangePerilTopic,
+			ExchangeType: ExchangeTypeTopic,
+			Key:          VisibleMovesPrefix + ".{game}.{username}",
+			BindingKey:   VisibleMovesPrefix + ".{game}.{username}",
+			Queue:        VisibleMovesPrefix + ".{game}.{username}",
+			Durable:      true,
+			Payload:      "army_move",
+			ContentType:  ContentTypeJSON,
+			Publisher:    "server",
+			Subscriber:   "client",
+		},
+		{
+			Name:         WorldArmyMovesQueue,
+			Description:  "The server validates every move against its world model.",
+			Exchange:     ExchangePerilTopic,
+			ExchangeType: ExchangeTypeTopic,
+			Key:          ArmyMovesPrefix + ".{game}.{username}",
+			BindingKey:   ArmyMovesPrefix + ".{game}.*",
+			Queue:        WorldArmyMovesQueue + ".{game}",
+			Durable:      true,
+			Payload:      "army_move",
+			ContentType:  ContentTypeJSON,
+			Publisher:    "client",
+			Subscriber:   "server",
+		},
+		{
+			Name:         WorldSyncPrefix,
+			Description:  "A client reports its whole army, for example after spawning a unit.",
+			Exchange:     ExchangePerilTopic,
+			ExchangeType: ExchangeTypeTopic,
+			Key:          WorldSyncPrefix + ".{game}.{username}",
+			BindingKey:   WorldSyncPrefix + ".{game}.*",
+			Queue:        WorldSyncQueue + ".{game}",
+			Durable:      true,
+			Payload:      "player_sync",
+			ContentType:  ContentTypeJSON,
+			Publisher:    "client",
+			Subscriber:   "server",
+		},
+		{
+			Name:         WorldStatePrefix,
+			Description:  "The server corrects one player's units after a rejected move or destroyed units.",
+			Exchange:     ExchangePerilTopic,
+			ExchangeType: ExchangeTypeTopic,
+			Key:          WorldStatePrefix + ".{game}.{username}",
+			BindingKey:   WorldStatePrefix + ".{game}.{username}",
+			Queue:        WorldStatePrefix + ".{game}.{username}",
+			Durable:      false,
+			Payload:      "state_delta",
+			ContentType:  ContentTypeJSON,
+			Publisher:    "server",
+			Subscriber:   "client",
+		},
+		{
+			Name:         WarRecognitionsPrefix,
+			Description:  "A client found an enemy army in one of its territories and declares war.",
+			Exchange:     ExchangePerilTopic,
+			ExchangeType: ExchangeTypeTopic,
+			Key:          WarRecognitionsPrefix + ".{game}.{username}",
+			BindingKey:   WarRecognitionsPrefix + ".{game}.#",
+			Queue:        WarRecognitionsPrefix + ".{game}",
+			Durable:      true,
+			Payload:      "recognition_of_war",
+			ContentType:  ContentTypeJSON,
+			Publisher:    "client",
+			Subscriber:   "server",
+		},
+		{
+			Name:         WarResolutionsPrefix,
+			Description:  "The server sends the outcome of a war to the attacker and to the defender.",
+			Exchange:     ExchangePerilTopic,
+			ExchangeType: ExchangeTypeTopic,
+			Key:          WarResolutionsPrefix + ".{game}.{username}",
+			BindingKey:   WarResolutionsPrefix + ".{game}.{username}",
+			Queue:        WarResolutionsPrefix + ".{game}.{username}",
+			Durable:      true,
+			Payload:      "war_resolution",
+			ContentType:  ContentTypeJSON,
+			Publisher:    "server",
+			Subscriber:   "client",
+		},
+		{
+			Name:         WarConfirmationsPrefix,
+			Description:  "Each combatant confirms it removed its casualties; the server logs the war once both have.",
+			Exchange:     ExchangePerilTopic,
+			ExchangeType: ExchangeTypeTopic,
+			Key:          WarConfirmationsPrefix + ".{game}.{username}",
+			BindingKey:   WarConfirmationsPrefix + ".{game}.*",
+			Queue:        WarConfirmationsPrefix + ".{game}",
+			Durable:      true,
+			Payload:      "war_confirmation",
+			ContentType:  ContentTypeJSON,
+			Publisher:    "client",
+			Subscriber:   "server",
+		},
+		{
+			Name:         HeartbeatPrefix,
+			Description:  "Clients say they are still playing every few seconds, and once more when they quit.",
+			Exchange:     ExchangePerilTopic,
+			ExchangeType: ExchangeTypeTopic,
+			Key:          HeartbeatPrefix + ".{game}.{username}",
+			BindingKey:   HeartbeatPrefix + ".{game}.*",
+			Queue:        WorldHeartbeatQueue + ".{game}",
+			Durable:      false,
+			Payload:      Heartbeat{}.SchemaName(),
+			ContentType:  ContentTypeJSON,
+			Publisher:    "client",
+			Subscriber:   "server",
+		},
+		{
+			Name:         PresenceKey,
+			Description:  "The server announces players joining, going AFK, leaving and being eliminated for staying away.",
+			Exchange:     ExchangePerilDirect,
+			ExchangeType: ExchangeTypeDirect,
+			Key:          PresenceKey + ".{game}",
+			BindingKey:   PresenceKey + ".{game}",
+			Queue:        PresenceKey + ".{game}.{username}",
+			Durable:      false,
+			Payload:      PresenceEvent{}.SchemaName(),
+			ContentType:  ContentTypeJSON,
+			Publisher:    "server",
+			Subscriber:   "client",
+		},
+		{
+			Name:         DiplomacyPrefix,
+			Description:  "The server passes on a proposal, acceptance or break of an alliance it accepted, without the sender's token, to both the other player and the sender; the player in the key records it when it arrives.",
+			Exchange:     ExchangePerilTopic,
+			ExchangeType: ExchangeTypeTopic,
+			Key:          DiplomacyPrefix + ".{game}.{username}",
+			BindingKey:   DiplomacyPrefix + ".{game}.{username}",
+			Queue:        DiplomacyPrefix + ".{game}.{username}",
+			Durable:      true,
+			Payload:      Diplomacy{}.SchemaName(),
+			ContentType:  ContentTypeJSON,
+			Publisher:    "server",
+			Subscriber:   "client",
+		},
+		{
+			Name:         WorldDiplomacyQueue,
+			Description:  "A player proposes, accepts or breaks an alliance, signed with their session token. The server refuses messages whose token does not match the sender, keeps track of alliances so that allies are not made to fight, and writes alliance changes to the game's log.",
+			Exchange:     ExchangePerilTopic,
+			ExchangeType: ExchangeTypeTopic,
+			Key:          DiplomacyRequestsPrefix + ".{game}.{username}",
+			BindingKey:   DiplomacyRequestsPrefix + ".{game}.*",
+			Queue:        WorldDiplomacyQueue + ".{game}",
+			Durable:      true,
+			Payload:      Diplomacy{}.SchemaName(),
+			ContentType:  ContentTypeJSON,
+			Publisher:    "client",
+			Subscriber:   "server",
+		},
+		{
+			Name:         GameLogSlug,
+			Description:  "Clients report chatter; the server appends it to the game's log, game-{game}.log.",
+			Exchange:     ExchangePerilTopic,
+			ExchangeType: ExchangeTypeTopic,
+			Key:          GameLogSlug + ".{game}.{username}",
+			BindingKey:   GameLogSlug + ".{game}.*",
+			Queue:        GameLogSlug + ".{game}",
+			Durable:      true,
+			Payload:      GameLog{}.SchemaName(),
+			ContentType:  ContentTypeGob,
+			Publisher:    "client",
+			Subscriber:   "server",
+		},
+	}
+}
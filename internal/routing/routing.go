@@ -1,16 +1,118 @@
 package routing
 
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
 const (
 	ArmyMovesPrefix = "army_moves"
 
+	// The server passes each move on to the players who can see it,
+	// redacted to what they can see.
+	VisibleMovesPrefix = "visible_moves"
+
 	WarRecognitionsPrefix = "war"
 
+	WarResolutionsPrefix = "war_resolutions"
+
+	WarConfirmationsPrefix = "war_confirmations"
+
 	PauseKey = "pause"
 
 	GameLogSlug = "game_logs"
+
+	WorldSyncPrefix = "world_sync"
+
+	WorldStatePrefix = "world_state"
+
+	ScenarioKey = "scenario"
+
+	EconomyKey = "economy"
+
+	TurnKey = "turn"
+
+	GameStatusKey = "game_status"
+
+	GameOverKey = "game_over"
+
+	HeartbeatPrefix = "heartbeat"
+
+	PresenceKey = "presence"
+
+	// Diplomacy is addressed to one player: diplomacy.<game>.<username>.
+	DiplomacyPrefix = "diplomacy"
+
+	// Players send their diplomacy to the server first, on
+	// diplomacy_requests.<game>.<username>. The server checks who sent it
+	// before passing it on.
+	DiplomacyRequestsPrefix = "diplomacy_requests"
+
+	// The server follows pause and resume messages, including scheduled
+	// ones, on its own queue.
+	WorldPauseQueue = "world." + PauseKey
+
+	// Clients ask the server to let them play; the server answers on the
+	// caller's reply-to queue.
+	JoinKey = "join"
+
+	// Lobby requests, answered like joins.
+	ListGamesKey  = "games.list"
+	CreateGameKey = "games.create"
+
+	// Queues the server's world model consumes from, alongside the clients.
+	WorldArmyMovesQueue = "world." + ArmyMovesPrefix
+	WorldSyncQueue      = "world." + WorldSyncPrefix
+	WorldHeartbeatQueue = "world." + HeartbeatPrefix
+	WorldDiplomacyQueue = "world." + DiplomacyPrefix
 )
 
+// DefaultGame is the game clients join unless they pick another one.
+const DefaultGame = "default"
+
 const (
 	ExchangePerilDirect = "peril_direct"
 	ExchangePerilTopic  = "peril_topic"
 )
+
+// GameKey scopes a routing key or queue name to one game, so that several
+// games can share a broker: GameKey(ArmyMovesPrefix, "default", "napoleon")
+// is "army_moves.default.napoleon".
+func GameKey(prefix, game string, rest ...string) string {
+	return strings.Join(append([]string{prefix, game}, rest...), ".")
+}
+
+// KeyUsername is the player at the end of a key such as
+// army_moves.<game>.<username>.
+func KeyUsername(key string) string {
+	return key[strings.LastIndex(key, ".")+1:]
+}
+
+// Signed is a message a player sends the server on a key ending in their
+// username, together with the session token they were given on join.
+type Signed interface {
+	Sender() (username, token string)
+}
+
+var (
+	gameIDPattern   = regexp.MustCompile(`^[a-z0-9_-]{1,32}$`)
+	usernamePattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,32}$`)
+)
+
+// ValidateGameID makes sure a game ID is safe to put in routing keys.
+func ValidateGameID(id string) error {
+	if !gameIDPattern.MatchString(id) {
+		return fmt.Errorf("game ID %q must be 1 to 32 lowercase letters, digits, dashes or underscores", id)
+	}
+	return nil
+}
+
+// ValidateUsername makes sure a username is safe to put in routing keys and
+// queue names.
+func ValidateUsername(username string) error {
+	if !usernamePattern.MatchString(username) {
+		return fmt.Errorf("username %q must be 1 to 32 letters, digits, dashes or underscores", username)
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+package routing
+
+import "github.com/bootdotdev/learn-pub-sub-starter/internal/schema"
+
+// Bump a version whenever a field is removed or changes kind, and register
+// an upcaster from the previous version below.
+const (
+	playingStateVersion = 1
+	gameLogVersion      = 1
+
+	scenarioAnnouncementVersion = 1
+	joinRequestVersion          = 1
+	joinResponseVersion         = 1
+	turnStateVersion            = 1
+	gameStatusVersion           = 1
+	listGamesRequestVersion     = 1
+	gameListVersion             = 1
+	createGameRequestVersion    = 1
+	createGameResponseVersion   = 1
+	heartbeatVersion            = 1
+	presenceEventVersion        = 1
+	diplomacyVersion            = 1
+)
+
+func init() {
+	schema.Register(PlayingState{})
+	schema.Register(GameLog{})
+	schema.Register(ScenarioAnnouncement{})
+	schema.Register(JoinRequest{})
+	schema.Register(JoinResponse{})
+	schema.Register(TurnState{})
+	schema.Register(GameStatus{})
+	schema.Register(ListGamesRequest{})
+	schema.Register(GameList{})
+	schema.Register(CreateGameRequest{})
+	schema.Register(CreateGameResponse{})
+	schema.Register(Heartbeat{})
+	schema.Register(PresenceEvent{})
+	schema.Register(Diplomacy{})
+}
+
+func (PlayingState) SchemaName() string { return "playing_state" }
+func (PlayingState) SchemaVersion() int { return playingStateVersion }
+
+func (GameLog) SchemaName() string { return "game_log" }
+func (GameLog) SchemaVersion() int { return gameLogVersion }
+
+func (ScenarioAnnouncement) SchemaName() string { return "scenario_announcement" }
+func (ScenarioAnnouncement) SchemaVersion() int { return scenarioAnnouncementVersion }
+
+func (JoinRequest) SchemaName() string { return "join_request" }
+func (JoinRequest) SchemaVersion() int { return joinRequestVersion }
+
+func (JoinResponse) SchemaName() string { return "join_response" }
+func (JoinResponse) SchemaVersion() int { return joinResponseVersion }
+
+func (TurnState) SchemaName() string { return "turn_state" }
+func (TurnState) SchemaVersion() int { return turnStateVersion }
+
+func (GameStatus) SchemaName() string { return "game_status" }
+func (GameStatus) SchemaVersion() int { return gameStatusVersion }
+
+func (ListGamesRequest) SchemaName() string { return "list_games_request" }
+func (ListGamesRequest) SchemaVersion() int { return listGamesRequestVersion }
+
+func (GameList) SchemaName() string { return "game_list" }
+func (GameList) SchemaVersion() int { return gameListVersion }
+
+func (CreateGameRequest) SchemaName() string { return "create_game_request" }
+func (CreateGameRequest) SchemaVersion() int { return createGameRequestVersion }
+
+func (CreateGameResponse) SchemaName() string { return "create_game_response" }
+func (CreateGameResponse) SchemaVersion() int { return createGameResponseVersion }
+
+func (Heartbeat) SchemaName() string { return "heartbeat" }
+func (Heartbeat) SchemaVersion() int { return heartbeatVersion }
+
+func (PresenceEvent) SchemaName() string { return "presence_event" }
+func (PresenceEvent) SchemaVersion() int { return presenceEventVersion }
+
+func (Diplomacy) SchemaName() string { return "diplomacy" }
+func (Diplomacy) SchemaVersion() int { return diplomacyVersion }
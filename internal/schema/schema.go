@@ -0,0 +1,121 @@
+package schema
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+const (
+	HeaderName    = "x-schema-name"
+	HeaderVersion = "x-schema-version"
+)
+
+// Messages published before versioning existed carry no header and are
+// treated as this version.
+const LegacyVersion = 1
+
+type Versioned interface {
+	SchemaName() string
+	SchemaVersion() int
+}
+
+// Upcaster rewrites a body encoded at one version into the next version,
+// keeping the same content type.
+type Upcaster func(contentType string, body []byte) ([]byte, error)
+
+var (
+	mu        sync.RWMutex
+	messages  = map[string]Versioned{}
+	upcasters = map[string]map[int]Upcaster{}
+)
+
+func Register(v Versioned) {
+	mu.Lock()
+	defer mu.Unlock()
+	messages[v.SchemaName()] = v
+}
+
+func RegisterUpcaster(name string, from int, fn Upcaster) {
+	mu.Lock()
+	defer mu.Unlock()
+	if upcasters[name] == nil {
+		upcasters[name] = map[int]Upcaster{}
+	}
+	upcasters[name][from] = fn
+}
+
+func Messages() []Versioned {
+	mu.RLock()
+	defer mu.RUnlock()
+	names := make([]string, 0, len(messages))
+	for name := range messages {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	vals := make([]Versioned, 0, len(names))
+	for _, name := range names {
+		vals = append(vals, messages[name])
+	}
+	return vals
+}
+
+func Lookup(name string) (Versioned, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+	v, ok := messages[name]
+	return v, ok
+}
+
+func HasUpcaster(name string, from int) bool {
+	mu.RLock()
+	defer mu.RUnlock()
+	_, ok := upcasters[name][from]
+	return ok
+}
+
+func Upcast(name string, from, to int, contentType string, body []byte) ([]byte, error) {
+	if from > to {
+		return nil, fmt.Errorf("%s v%d is newer than supported v%d", name, from, to)
+	}
+	for v := from; v < to; v++ {
+		mu.RLock()
+		fn, ok := upcasters[name][v]
+		mu.RUnlock()
+		if !ok {
+			return nil, fmt.Errorf("no upcaster for %s v%d", name, v)
+		}
+		var err error
+		body, err = fn(contentType, body)
+		if err != nil {
+			return nil, fmt.Errorf("upcasting %s v%d: %v", name, v, err)
+		}
+	}
+	return body, nil
+}
+
+// HeaderInt reads a numeric header regardless of the integer width the
+// broker or a stored table decoded it as.
+func HeaderInt(v any) (int, bool) {
+	switch n := v.(type) {
+	case int:
+		return n, true
+	case int8:
+		return int(n), true
+	case int16:
+		return int(n), true
+	case int32:
+		return int(n), true
+	case int64:
+		return int(n), true
+	case uint8:
+		return int(n), true
+	case uint16:
+		return int(n), true
+	case uint32:
+		return int(n), true
+	case float64:
+		return int(n), true
+	}
+	return 0, false
+}
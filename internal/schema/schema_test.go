@@ -0,0 +1,71 @@
+package schema
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type unitV1 struct {
+	ID   int
+	Rank string
+}
+
+type moveV1 struct {
+	Units []unitV1
+}
+
+func (moveV1) SchemaName() string { return "test_move" }
+func (moveV1) SchemaVersion() int { return 1 }
+
+type unitV2 struct {
+	ID   string
+	Rank string
+}
+
+type moveV2 struct {
+	Units []unitV2
+	Turn  int
+}
+
+func (moveV2) SchemaName() string { return "test_move" }
+func (moveV2) SchemaVersion() int { return 1 }
+
+type moveV2Bumped struct {
+	moveV2
+}
+
+func (moveV2Bumped) SchemaVersion() int { return 2 }
+
+type moveV1Added struct {
+	Units []unitV1
+	Turn  int
+}
+
+func (moveV1Added) SchemaName() string { return "test_move" }
+func (moveV1Added) SchemaVersion() int { return 1 }
+
+func TestCheckAllowsAddedFields(t *testing.T) {
+	lock := NewLock([]Versioned{moveV1{}})
+	require.Empty(t, Check(lock, []Versioned{moveV1Added{}}))
+}
+
+func TestCheckRejectsKindChangeWithoutBump(t *testing.T) {
+	lock := NewLock([]Versioned{moveV1{}})
+	problems := Check(lock, []Versioned{moveV2{}})
+	require.Equal(t, []string{"test_move: field Units[].ID changed from int to string without a version bump"}, problems)
+}
+
+func TestCheckRequiresUpcasterForBump(t *testing.T) {
+	lock := NewLock([]Versioned{moveV1{}})
+	require.Equal(t, []string{"test_move: no upcaster from v1 to v2"}, Check(lock, []Versioned{moveV2Bumped{}}))
+
+	RegisterUpcaster("test_move", 1, func(contentType string, body []byte) ([]byte, error) {
+		return append(body, '!'), nil
+	})
+	require.Empty(t, Check(lock, []Versioned{moveV2Bumped{}}))
+
+	body, err := Upcast("test_move", 1, 2, "application/json", []byte("{}"))
+	require.NoError(t, err)
+	require.Equal(t, "{}!", string(body))
+}
@@ -0,0 +1,178 @@
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+)
+
+type Shape struct {
+	Version int               `json:"version"`
+	Fields  map[string]string `json:"fields"`
+}
+
+type Lock struct {
+	Messages map[string]Shape `json:"messages"`
+}
+
+// Describe flattens the exported fields of a message into paths such as
+// "Player.Units{}.Location" mapped to the kind the encoders care about.
+func Describe(v Versioned) Shape {
+	fields := map[string]string{}
+	describeType(reflect.TypeOf(v), "", fields, map[reflect.Type]bool{})
+	return Shape{
+		Version: v.SchemaVersion(),
+		Fields:  fields,
+	}
+}
+
+func describeType(t reflect.Type, path string, fields map[string]string, seen map[reflect.Type]bool) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if path != "" {
+		fields[path] = kindName(t)
+	}
+	switch t.Kind() {
+	case reflect.Struct:
+		if t.PkgPath() == "time" || seen[t] {
+			return
+		}
+		seen[t] = true
+		defer delete(seen, t)
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if !f.IsExported() || f.Tag.Get("json") == "-" {
+				continue
+			}
+			describeType(f.Type, join(path, f.Name), fields, seen)
+		}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return
+		}
+		describeType(t.Elem(), path+"[]", fields, seen)
+	case reflect.Map:
+		describeType(t.Elem(), path+"{}", fields, seen)
+	}
+}
+
+func kindName(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.Struct:
+		if t.PkgPath() == "time" {
+			return "time." + t.Name()
+		}
+		return "struct"
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return "bytes"
+		}
+		return "slice"
+	case reflect.Map:
+		return "map[" + kindName(t.Key()) + "]"
+	}
+	return t.Kind().String()
+}
+
+func join(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+// Breaking lists the changes from old to cur that a consumer still decoding
+// the old shape would not survive. Added fields are ignored by both gob and
+// JSON, so only removals and kind changes count.
+func Breaking(old, cur Shape) []string {
+	problems := []string{}
+	for _, path := range sortedKeys(old.Fields) {
+		kind, ok := cur.Fields[path]
+		if !ok {
+			problems = append(problems, fmt.Sprintf("field %s was removed", path))
+			continue
+		}
+		if kind != old.Fields[path] {
+			problems = append(problems, fmt.Sprintf("field %s changed from %s to %s", path, old.Fields[path], kind))
+		}
+	}
+	return problems
+}
+
+// Check compares the registered messages against a lock file written from
+// the previous release.
+func Check(lock Lock, msgs []Versioned) []string {
+	current := map[string]Shape{}
+	for _, m := range msgs {
+		current[m.SchemaName()] = Describe(m)
+	}
+	problems := []string{}
+	for _, name := range sortedKeys(lock.Messages) {
+		old := lock.Messages[name]
+		cur, ok := current[name]
+		if !ok {
+			problems = append(problems, fmt.Sprintf("%s: message was removed", name))
+			continue
+		}
+		switch {
+		case cur.Version < old.Version:
+			problems = append(problems, fmt.Sprintf("%s: version went back from v%d to v%d", name, old.Version, cur.Version))
+		case cur.Version == old.Version:
+			for _, p := range Breaking(old, cur) {
+				problems = append(problems, fmt.Sprintf("%s: %s without a version bump", name, p))
+			}
+		default:
+			for v := old.Version; v < cur.Version; v++ {
+				if !HasUpcaster(name, v) {
+					problems = append(problems, fmt.Sprintf("%s: no upcaster from v%d to v%d", name, v, v+1))
+				}
+			}
+		}
+	}
+	return problems
+}
+
+func NewLock(msgs []Versioned) Lock {
+	lock := Lock{Messages: map[string]Shape{}}
+	for _, m := range msgs {
+		lock.Messages[m.SchemaName()] = Describe(m)
+	}
+	return lock
+}
+
+func ReadLock(path string) (Lock, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Lock{}, fmt.Errorf("could not read lock file: %v", err)
+	}
+	lock := Lock{}
+	err = json.Unmarshal(data, &lock)
+	if err != nil {
+		return Lock{}, fmt.Errorf("could not parse lock file %s: %v", path, err)
+	}
+	return lock, nil
+}
+
+func WriteLock(path string, lock Lock) error {
+	data, err := json.MarshalIndent(lock, "", "  ")
+	if err != nil {
+		return err
+	}
+	err = os.WriteFile(path, append(data, '\n'), 0644)
+	if err != nil {
+		return fmt.Errorf("could not write lock file: %v", err)
+	}
+	return nil
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}